@@ -1,3 +1,6 @@
+-- Runs only when the database volume is created; schema changes also go to
+-- db/upgrade.sql for existing databases.
+
 CREATE TABLE IF NOT EXISTS currencies (
     code VARCHAR(3) PRIMARY KEY
 );
@@ -10,7 +13,10 @@ CREATE TABLE IF NOT EXISTS rates (
     id SERIAL PRIMARY KEY,
     currency1 VARCHAR(3) NOT NULL,
     currency2 VARCHAR(3) NOT NULL,
-    rate DOUBLE PRECISION,
+    rate NUMERIC,
+    provider VARCHAR(32),
+    sources TEXT[],
+    spread NUMERIC,
     update_time TIMESTAMP,
     CONSTRAINT fk_rates_currency1 FOREIGN KEY (currency1) REFERENCES currencies(code),
     CONSTRAINT fk_rates_currency2 FOREIGN KEY (currency2) REFERENCES currencies(code),
@@ -24,12 +30,67 @@ CROSS JOIN currencies c2
 WHERE c1.code != c2.code
 ON CONFLICT (currency1, currency2) DO NOTHING;
 
-CREATE TYPE reqstatus AS ENUM ('submitted', 'ok', 'failed');
+CREATE TABLE IF NOT EXISTS rate_history (
+    id BIGSERIAL PRIMARY KEY,
+    currency1 VARCHAR(3) NOT NULL,
+    currency2 VARCHAR(3) NOT NULL,
+    rate NUMERIC NOT NULL,
+    provider VARCHAR(32),
+    sources TEXT[],
+    spread NUMERIC,
+    update_time TIMESTAMP NOT NULL,
+    CONSTRAINT fk_history_currency1 FOREIGN KEY (currency1) REFERENCES currencies(code),
+    CONSTRAINT fk_history_currency2 FOREIGN KEY (currency2) REFERENCES currencies(code)
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_history_pair_time ON rate_history (currency1, currency2, update_time);
+
+CREATE TYPE reqstatus AS ENUM ('submitted', 'ok', 'failed', 'rejected');
 CREATE TABLE IF NOT EXISTS update_requests (
     id SERIAL PRIMARY KEY,
     currency1 VARCHAR(3) NOT NULL,
     currency2 VARCHAR(3) NOT NULL,
     request_status reqstatus NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    completed_at TIMESTAMP,
+    failure_reason TEXT,
+    rate NUMERIC,
+    provider VARCHAR(32),
+    sources TEXT[],
+    spread NUMERIC,
+    update_time TIMESTAMP,
+    callback_url TEXT,
+    lease_owner TEXT,
+    lease_expires_at TIMESTAMP,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    webhook_due_at TIMESTAMP,
+    webhook_attempts INTEGER NOT NULL DEFAULT 0,
     CONSTRAINT fk_update_currency1 FOREIGN KEY (currency1) REFERENCES currencies(code),
     CONSTRAINT fk_update_currency2 FOREIGN KEY (currency2) REFERENCES currencies(code)
 );
+
+CREATE INDEX IF NOT EXISTS idx_update_requests_submitted ON update_requests (id) WHERE request_status = 'submitted';
+CREATE INDEX IF NOT EXISTS idx_update_requests_webhook_due ON update_requests (webhook_due_at) WHERE webhook_due_at IS NOT NULL;
+
+-- One server refreshes a scheduled pair per tick: it claims the pair until
+-- shortly before the next one.
+CREATE TABLE IF NOT EXISTS scheduled_refreshes (
+    currency1 VARCHAR(3) NOT NULL,
+    currency2 VARCHAR(3) NOT NULL,
+    claimed_until TIMESTAMP NOT NULL,
+    PRIMARY KEY (currency1, currency2)
+);
+
+CREATE TABLE IF NOT EXISTS webhook_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    request_id INTEGER NOT NULL,
+    callback_url TEXT NOT NULL,
+    attempt INTEGER NOT NULL,
+    attempted_at TIMESTAMP NOT NULL,
+    status_code INTEGER,
+    error TEXT,
+    CONSTRAINT fk_webhook_request FOREIGN KEY (request_id) REFERENCES update_requests(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_attempts_request ON webhook_attempts (request_id);
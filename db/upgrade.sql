@@ -0,0 +1,67 @@
+-- Brings a database created by an earlier db/init.sql up to date, as Postgres
+-- runs init.sql only when its volume is created. Every statement can run
+-- again, so the script is safe on a database that is already current:
+--   docker compose exec -T db psql -U postgres -d esr -v ON_ERROR_STOP=1 < db/upgrade.sql
+
+ALTER TABLE rates ALTER COLUMN rate TYPE NUMERIC USING rate::numeric;
+ALTER TABLE rates
+    ADD COLUMN IF NOT EXISTS provider VARCHAR(32),
+    ADD COLUMN IF NOT EXISTS sources TEXT[],
+    ADD COLUMN IF NOT EXISTS spread NUMERIC;
+
+CREATE TABLE IF NOT EXISTS rate_history (
+    id BIGSERIAL PRIMARY KEY,
+    currency1 VARCHAR(3) NOT NULL,
+    currency2 VARCHAR(3) NOT NULL,
+    rate NUMERIC NOT NULL,
+    provider VARCHAR(32),
+    sources TEXT[],
+    spread NUMERIC,
+    update_time TIMESTAMP NOT NULL,
+    CONSTRAINT fk_history_currency1 FOREIGN KEY (currency1) REFERENCES currencies(code),
+    CONSTRAINT fk_history_currency2 FOREIGN KEY (currency2) REFERENCES currencies(code)
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_history_pair_time ON rate_history (currency1, currency2, update_time);
+
+ALTER TYPE reqstatus ADD VALUE IF NOT EXISTS 'rejected';
+ALTER TABLE update_requests
+    ADD COLUMN IF NOT EXISTS created_at TIMESTAMP NOT NULL DEFAULT now(),
+    ADD COLUMN IF NOT EXISTS completed_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS failure_reason TEXT,
+    ADD COLUMN IF NOT EXISTS rate NUMERIC,
+    ADD COLUMN IF NOT EXISTS provider VARCHAR(32),
+    ADD COLUMN IF NOT EXISTS sources TEXT[],
+    ADD COLUMN IF NOT EXISTS spread NUMERIC,
+    ADD COLUMN IF NOT EXISTS update_time TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS callback_url TEXT,
+    ADD COLUMN IF NOT EXISTS lease_owner TEXT,
+    ADD COLUMN IF NOT EXISTS lease_expires_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS last_error TEXT,
+    ADD COLUMN IF NOT EXISTS webhook_due_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS webhook_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE update_requests ALTER COLUMN rate TYPE NUMERIC USING rate::numeric;
+
+CREATE INDEX IF NOT EXISTS idx_update_requests_submitted ON update_requests (id) WHERE request_status = 'submitted';
+CREATE INDEX IF NOT EXISTS idx_update_requests_webhook_due ON update_requests (webhook_due_at) WHERE webhook_due_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS scheduled_refreshes (
+    currency1 VARCHAR(3) NOT NULL,
+    currency2 VARCHAR(3) NOT NULL,
+    claimed_until TIMESTAMP NOT NULL,
+    PRIMARY KEY (currency1, currency2)
+);
+
+CREATE TABLE IF NOT EXISTS webhook_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    request_id INTEGER NOT NULL,
+    callback_url TEXT NOT NULL,
+    attempt INTEGER NOT NULL,
+    attempted_at TIMESTAMP NOT NULL,
+    status_code INTEGER,
+    error TEXT,
+    CONSTRAINT fk_webhook_request FOREIGN KEY (request_id) REFERENCES update_requests(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_attempts_request ON webhook_attempts (request_id);
@@ -0,0 +1,446 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/artem98/ExchangeRateService/server/constants"
+	"github.com/artem98/ExchangeRateService/server/rates/external"
+	"github.com/artem98/ExchangeRateService/server/rates/scheduler"
+	"github.com/artem98/ExchangeRateService/server/rates/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds the settings of the server. Load fills it from the defaults, a
+// YAML or TOML file, environment variables and command line flags, each
+// overriding the previous ones.
+type Config struct {
+	Server    ServerConfig    `yaml:"server" toml:"server"`
+	Database  DatabaseConfig  `yaml:"database" toml:"database"`
+	Worker    WorkerConfig    `yaml:"worker" toml:"worker"`
+	Providers ProvidersConfig `yaml:"providers" toml:"providers"`
+	Retry     RetryConfig     `yaml:"retry" toml:"retry"`
+	Refresh   RefreshConfig   `yaml:"refresh" toml:"refresh"`
+	Webhooks  WebhooksConfig  `yaml:"webhooks" toml:"webhooks"`
+}
+
+type ServerConfig struct {
+	HttpAddress     string        `yaml:"http_address" toml:"http_address"`
+	GrpcAddress     string        `yaml:"grpc_address" toml:"grpc_address"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	// AdminToken enables POST /admin/reload on AdminAddress for requests
+	// bearing it. AdminAddress is kept apart from HttpAddress so it can stay
+	// unreachable from outside the host.
+	AdminToken   string `yaml:"admin_token" toml:"admin_token"`
+	AdminAddress string `yaml:"admin_address" toml:"admin_address"`
+}
+
+type DatabaseConfig struct {
+	Host     string `yaml:"host" toml:"host"`
+	Port     int    `yaml:"port" toml:"port"`
+	User     string `yaml:"user" toml:"user"`
+	Password string `yaml:"password" toml:"password"`
+	Name     string `yaml:"name" toml:"name"`
+	SSLMode  string `yaml:"sslmode" toml:"sslmode"`
+}
+
+type WorkerConfig struct {
+	PoolSize  int           `yaml:"pool_size" toml:"pool_size"`
+	QueueSize int           `yaml:"queue_size" toml:"queue_size"`
+	CacheTTL  time.Duration `yaml:"cache_ttl" toml:"cache_ttl"`
+}
+
+type ProvidersConfig struct {
+	Names       []string `yaml:"names" toml:"names"`
+	Aggregation string   `yaml:"aggregation" toml:"aggregation"`
+	RatesFile   string   `yaml:"rates_file" toml:"rates_file"`
+	Pivots      []string `yaml:"pivots" toml:"pivots"`
+	// ConsensusDeviation is the largest relative distance from the median of
+	// an answer kept by the consensus aggregation, which needs at least
+	// ConsensusMinSources of them.
+	ConsensusDeviation  float64 `yaml:"consensus_deviation" toml:"consensus_deviation"`
+	ConsensusMinSources int     `yaml:"consensus_min_sources" toml:"consensus_min_sources"`
+}
+
+// RetryConfig sets the backoff of rate updates failing transiently, see
+// worker.RetryPolicy.
+type RetryConfig struct {
+	MaxAttempts int           `yaml:"max_attempts" toml:"max_attempts"`
+	BaseDelay   time.Duration `yaml:"base_delay" toml:"base_delay"`
+	MaxDelay    time.Duration `yaml:"max_delay" toml:"max_delay"`
+}
+
+type RefreshConfig struct {
+	// Schedules uses the syntax of scheduler.ParseEntries.
+	Schedules string `yaml:"schedules" toml:"schedules"`
+	// Publication is "none" to refresh scheduled pairs even when their rate is
+	// newer than the latest publication.
+	Publication string `yaml:"publication_schedule" toml:"publication_schedule"`
+}
+
+type WebhooksConfig struct {
+	// Secret enables callback URLs when set.
+	Secret string `yaml:"secret" toml:"secret"`
+}
+
+const redacted = "<redacted>"
+
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			HttpAddress:     constants.DefaultHttpAddress,
+			GrpcAddress:     constants.DefaultGrpcAddress,
+			ShutdownTimeout: constants.DefaultShutdownTimeout,
+			AdminAddress:    constants.DefaultAdminAddress,
+		},
+		Database: DatabaseConfig{
+			Host:     constants.DefaultDbHost,
+			Port:     constants.DefaultDbPort,
+			User:     constants.DefaultDbUser,
+			Password: constants.DefaultDbPassword,
+			Name:     constants.DefaultDbName,
+			SSLMode:  constants.DefaultDbSSLMode,
+		},
+		Worker: WorkerConfig{
+			PoolSize:  constants.DefaultWorkerPoolSize,
+			QueueSize: constants.DefaultWorkerQueueSize,
+			CacheTTL:  constants.DefaultCacheTTL,
+		},
+		Providers: ProvidersConfig{
+			Names:       splitList(constants.DefaultRateProviders),
+			Aggregation: constants.DefaultRateAggregation,
+			RatesFile:   constants.DefaultRatesFilePath,
+			Pivots:      splitList(constants.DefaultPivotCurrencies),
+
+			ConsensusDeviation:  constants.DefaultConsensusDeviation,
+			ConsensusMinSources: constants.DefaultConsensusMinSources,
+		},
+		Retry: RetryConfig{
+			MaxAttempts: constants.RetryMaxAttempts,
+			BaseDelay:   constants.RetryBaseDelay,
+			MaxDelay:    constants.RetryMaxDelay,
+		},
+		Refresh: RefreshConfig{
+			Publication: constants.DefaultPublicationSchedule,
+		},
+	}
+}
+
+// Load reads the configuration for the command line arguments args, without
+// the program name. The file is given by -config or CONFIG_FILE, its format
+// by its extension.
+func Load(args []string) (Config, error) {
+	return load(args, os.Getenv)
+}
+
+func load(args []string, getenv func(string) string) (Config, error) {
+	// The file comes before the flags, so they are parsed once only to find it.
+	scratch := Default()
+	path := getenv("CONFIG_FILE")
+	flags := makeFlagSet(&scratch, &path)
+	if err := flags.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	cfg := Default()
+	if path != "" {
+		if err := cfg.readFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+	if err := cfg.readEnv(getenv); err != nil {
+		return Config{}, err
+	}
+
+	flags = makeFlagSet(&cfg, &path)
+	flags.SetOutput(io.Discard)
+	if err := flags.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	cfg.normalize()
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(data), c)
+		if err != nil {
+			return fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("invalid config file %s: unknown setting %s", path, undecoded[0])
+		}
+	default:
+		return fmt.Errorf("unsupported config file %s: expected .yaml, .yml or .toml", path)
+	}
+	return nil
+}
+
+func (c *Config) readEnv(getenv func(string) string) error {
+	var errs []error
+	setString := func(name string, target *string) {
+		if value := getenv(name); value != "" {
+			*target = value
+		}
+	}
+	setList := func(name string, target *[]string) {
+		if value := getenv(name); value != "" {
+			*target = splitList(value)
+		}
+	}
+	setInt := func(name string, target *int) {
+		if value := getenv(name); value != "" {
+			number, err := strconv.Atoi(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s must be a number, got %q", name, value))
+				return
+			}
+			*target = number
+		}
+	}
+	setFloat := func(name string, target *float64) {
+		if value := getenv(name); value != "" {
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s must be a number, got %q", name, value))
+				return
+			}
+			*target = number
+		}
+	}
+	setDuration := func(name string, target *time.Duration) {
+		if value := getenv(name); value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s must be a duration like 30s, got %q", name, value))
+				return
+			}
+			*target = d
+		}
+	}
+
+	setString("HTTP_ADDRESS", &c.Server.HttpAddress)
+	setString("GRPC_ADDRESS", &c.Server.GrpcAddress)
+	setDuration("SHUTDOWN_TIMEOUT", &c.Server.ShutdownTimeout)
+	setString("ADMIN_TOKEN", &c.Server.AdminToken)
+	setString("ADMIN_ADDRESS", &c.Server.AdminAddress)
+	setString("DB_HOST", &c.Database.Host)
+	setInt("DB_PORT", &c.Database.Port)
+	setString("DB_USER", &c.Database.User)
+	setString("DB_PASSWORD", &c.Database.Password)
+	setString("DB_NAME", &c.Database.Name)
+	setString("DB_SSLMODE", &c.Database.SSLMode)
+	setInt("WORKER_POOL_SIZE", &c.Worker.PoolSize)
+	setInt("WORKER_QUEUE_SIZE", &c.Worker.QueueSize)
+	setDuration("CACHE_TTL", &c.Worker.CacheTTL)
+	setList("RATE_PROVIDER", &c.Providers.Names)
+	setString("RATE_AGGREGATION", &c.Providers.Aggregation)
+	setString("RATES_FILE", &c.Providers.RatesFile)
+	setList("TRIANGULATION_PIVOTS", &c.Providers.Pivots)
+	setFloat("CONSENSUS_DEVIATION", &c.Providers.ConsensusDeviation)
+	setInt("CONSENSUS_MIN_SOURCES", &c.Providers.ConsensusMinSources)
+	setInt("RETRY_MAX_ATTEMPTS", &c.Retry.MaxAttempts)
+	setDuration("RETRY_BASE_DELAY", &c.Retry.BaseDelay)
+	setDuration("RETRY_MAX_DELAY", &c.Retry.MaxDelay)
+	setString("REFRESH_SCHEDULES", &c.Refresh.Schedules)
+	setString("PUBLICATION_SCHEDULE", &c.Refresh.Publication)
+	setString("WEBHOOK_SECRET", &c.Webhooks.Secret)
+	return errors.Join(errs...)
+}
+
+// makeFlagSet binds the flags to the fields of c. Passwords and secrets have no
+// flags, as the arguments of a process are visible to other users.
+func makeFlagSet(c *Config, path *string) *flag.FlagSet {
+	flags := flag.NewFlagSet("server", flag.ContinueOnError)
+	flags.StringVar(path, "config", *path, "YAML or TOML config `file`")
+	flags.StringVar(&c.Server.HttpAddress, "http-address", c.Server.HttpAddress, "HTTP listen address")
+	flags.StringVar(&c.Server.GrpcAddress, "grpc-address", c.Server.GrpcAddress, "gRPC listen address")
+	flags.StringVar(&c.Server.AdminAddress, "admin-address", c.Server.AdminAddress, "admin listen address, used when ADMIN_TOKEN is set")
+	flags.DurationVar(&c.Server.ShutdownTimeout, "shutdown-timeout", c.Server.ShutdownTimeout, "time to finish calls and queued updates on shutdown")
+	flags.StringVar(&c.Database.Host, "db-host", c.Database.Host, "database host")
+	flags.IntVar(&c.Database.Port, "db-port", c.Database.Port, "database port")
+	flags.StringVar(&c.Database.User, "db-user", c.Database.User, "database user")
+	flags.StringVar(&c.Database.Name, "db-name", c.Database.Name, "database name")
+	flags.StringVar(&c.Database.SSLMode, "db-sslmode", c.Database.SSLMode, "database sslmode")
+	flags.IntVar(&c.Worker.PoolSize, "worker-pool-size", c.Worker.PoolSize, "number of update requests processed concurrently")
+	flags.IntVar(&c.Worker.QueueSize, "worker-queue-size", c.Worker.QueueSize, "number of update jobs waiting for the worker")
+	flags.DurationVar(&c.Worker.CacheTTL, "cache-ttl", c.Worker.CacheTTL, "how long an update request of a pair is reused")
+	flags.Func("providers", "comma-separated rate providers tried in order", func(value string) error {
+		c.Providers.Names = splitList(value)
+		return nil
+	})
+	flags.StringVar(&c.Providers.Aggregation, "rate-aggregation", c.Providers.Aggregation, "fallback or consensus")
+	flags.StringVar(&c.Providers.RatesFile, "rates-file", c.Providers.RatesFile, "JSON file read by the file provider")
+	flags.Func("pivots", "comma-separated triangulation pivot currencies", func(value string) error {
+		c.Providers.Pivots = splitList(value)
+		return nil
+	})
+	flags.Float64Var(&c.Providers.ConsensusDeviation, "consensus-deviation", c.Providers.ConsensusDeviation, "largest relative distance from the median kept by the consensus, e.g. 0.01")
+	flags.IntVar(&c.Providers.ConsensusMinSources, "consensus-min-sources", c.Providers.ConsensusMinSources, "answers the consensus needs within the deviation")
+	flags.IntVar(&c.Retry.MaxAttempts, "retry-max-attempts", c.Retry.MaxAttempts, "attempts of a rate update failing transiently, 1 to never retry")
+	flags.DurationVar(&c.Retry.BaseDelay, "retry-base-delay", c.Retry.BaseDelay, "delay before the first retry, doubled with every attempt")
+	flags.DurationVar(&c.Retry.MaxDelay, "retry-max-delay", c.Retry.MaxDelay, "longest delay between retries")
+	flags.StringVar(&c.Refresh.Schedules, "refresh-schedules", c.Refresh.Schedules, "pairs refreshed on schedules, e.g. \"EUR/USD=@every 1m\"")
+	flags.StringVar(&c.Refresh.Publication, "publication-schedule", c.Refresh.Publication, "when the upstream publishes new rates, or none")
+	return flags
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (c *Config) normalize() {
+	for i, pivot := range c.Providers.Pivots {
+		c.Providers.Pivots[i] = strings.ToUpper(strings.TrimSpace(pivot))
+	}
+	if c.Refresh.Publication == "" {
+		c.Refresh.Publication = constants.DefaultPublicationSchedule
+	}
+}
+
+// Validate reports every setting that is out of range.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.HttpAddress != "", "server.http_address must not be empty")
+	check(c.Server.GrpcAddress != "", "server.grpc_address must not be empty")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	check(c.Server.AdminToken == "" || c.Server.AdminAddress != "", "server.admin_address must not be empty when server.admin_token is set")
+	check(c.Server.AdminAddress == "" || c.Server.AdminAddress != c.Server.HttpAddress, "server.admin_address must differ from server.http_address")
+	check(c.Database.Host != "", "database.host must not be empty")
+	check(c.Database.Port > 0 && c.Database.Port <= 65535, "database.port must be between 1 and 65535, got %d", c.Database.Port)
+	check(c.Database.User != "", "database.user must not be empty")
+	check(c.Database.Name != "", "database.name must not be empty")
+	check(c.Database.SSLMode != "", "database.sslmode must not be empty")
+	check(c.Worker.PoolSize >= 1, "worker.pool_size must be at least 1, got %d", c.Worker.PoolSize)
+	check(c.Worker.QueueSize >= 1, "worker.queue_size must be at least 1, got %d", c.Worker.QueueSize)
+	check(c.Worker.CacheTTL > 0, "worker.cache_ttl must be positive")
+	check(len(c.Providers.Names) > 0, "providers.names must list at least one provider")
+	for _, name := range c.Providers.Names {
+		check(slices.Contains(external.ProviderNames(), name),
+			"providers.names has unknown provider %q, available: %v", name, external.ProviderNames())
+	}
+	check(c.Providers.Aggregation == external.AggregationFallback || c.Providers.Aggregation == external.AggregationConsensus,
+		"providers.aggregation must be %s or %s, got %q", external.AggregationFallback, external.AggregationConsensus, c.Providers.Aggregation)
+	check(c.Providers.ConsensusDeviation > 0, "providers.consensus_deviation must be positive, got %v", c.Providers.ConsensusDeviation)
+	check(c.Providers.ConsensusMinSources >= 1, "providers.consensus_min_sources must be at least 1, got %d", c.Providers.ConsensusMinSources)
+	for _, pivot := range c.Providers.Pivots {
+		_, err := utils.ParseCurrencyCode(pivot)
+		check(err == nil, "providers.pivots: %v", err)
+	}
+	check(c.Retry.MaxAttempts >= 1, "retry.max_attempts must be at least 1, got %d", c.Retry.MaxAttempts)
+	check(c.Retry.BaseDelay > 0, "retry.base_delay must be positive")
+	check(c.Retry.MaxDelay >= c.Retry.BaseDelay, "retry.max_delay must not be shorter than retry.base_delay")
+	_, err := scheduler.ParseEntries(c.Refresh.Schedules)
+	check(err == nil, "refresh.schedules: %v", err)
+	if c.Refresh.Publication != "none" {
+		_, err := scheduler.ParseSchedule(c.Refresh.Publication)
+		check(err == nil, "refresh.publication_schedule must be a schedule or none: %v", err)
+	}
+	return errors.Join(errs...)
+}
+
+// DSN returns the connection string of the database.
+func (c DatabaseConfig) DSN() string {
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     net.JoinHostPort(c.Host, strconv.Itoa(c.Port)),
+		Path:     "/" + c.Name,
+		RawQuery: url.Values{"sslmode": {c.SSLMode}}.Encode(),
+	}
+	return dsn.String()
+}
+
+// Options returns the settings passed to external.MakeProviderChain.
+func (c ProvidersConfig) Options() external.ProviderOptions {
+	return external.ProviderOptions{
+		RatesFile:           c.RatesFile,
+		ConsensusDeviation:  c.ConsensusDeviation,
+		ConsensusMinSources: c.ConsensusMinSources,
+	}
+}
+
+// NeedsRestart lists the settings that differ in next and only take effect
+// after a restart, as they are used to open listeners and connections, to
+// size the worker or by the jobs already queued. The others are applied by a
+// reload.
+func (c Config) NeedsRestart(next Config) []string {
+	var settings []string
+	changed := func(differs bool, setting string) {
+		if differs {
+			settings = append(settings, setting)
+		}
+	}
+
+	changed(c.Server != next.Server, "server")
+	changed(c.Database != next.Database, "database")
+	changed(c.Worker.PoolSize != next.Worker.PoolSize, "worker.pool_size")
+	changed(c.Worker.QueueSize != next.Worker.QueueSize, "worker.queue_size")
+	changed(!slices.Equal(c.Providers.Pivots, next.Providers.Pivots), "providers.pivots")
+	changed(c.Retry != next.Retry, "retry")
+	changed(c.Webhooks != next.Webhooks, "webhooks")
+	return settings
+}
+
+// Redacted returns a copy of c without the password and the secrets.
+func (c Config) Redacted() Config {
+	if c.Database.Password != "" {
+		c.Database.Password = redacted
+	}
+	if c.Webhooks.Secret != "" {
+		c.Webhooks.Secret = redacted
+	}
+	if c.Server.AdminToken != "" {
+		c.Server.AdminToken = redacted
+	}
+	return c
+}
+
+// String formats the redacted configuration as YAML.
+func (c Config) String() string {
+	data, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
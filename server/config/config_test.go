@@ -0,0 +1,219 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artem98/ExchangeRateService/server/rates/external"
+)
+
+func env(values map[string]string) func(string) string {
+	return func(name string) string { return values[name] }
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := load(nil, env(nil))
+	if err != nil {
+		t.Fatalf("expected defaults to be valid, got %v", err)
+	}
+	if cfg.Server.HttpAddress != ":8080" || cfg.Worker.PoolSize != 4 || cfg.Worker.CacheTTL != 30*time.Second {
+		t.Fatalf("unexpected defaults: %+v", cfg)
+	}
+	if !slices.Equal(cfg.Providers.Names, []string{"frankfurter", "erapi"}) {
+		t.Fatalf("unexpected default providers: %v", cfg.Providers.Names)
+	}
+}
+
+func TestLoad_FileEnvAndFlagsOverrideInOrder(t *testing.T) {
+	path := writeFile(t, "server.yaml", `
+server:
+  http_address: ":8000"
+  grpc_address: ":9000"
+database:
+  host: file-host
+  password: from-file
+worker:
+  pool_size: 2
+  cache_ttl: 1m
+providers:
+  names: [fake]
+  pivots: [usd]
+`)
+	cfg, err := load(
+		[]string{"-worker-pool-size", "8", "-http-address", ":7000"},
+		env(map[string]string{"CONFIG_FILE": path, "DB_HOST": "env-host", "WORKER_POOL_SIZE": "6"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Server.HttpAddress != ":7000" || cfg.Server.GrpcAddress != ":9000" {
+		t.Errorf("expected the flag to override the file, got %+v", cfg.Server)
+	}
+	if cfg.Database.Host != "env-host" || cfg.Database.Password != "from-file" {
+		t.Errorf("expected the env to override the file, got %+v", cfg.Database)
+	}
+	if cfg.Worker.PoolSize != 8 || cfg.Worker.CacheTTL != time.Minute {
+		t.Errorf("expected the flag to override the env, got %+v", cfg.Worker)
+	}
+	if !slices.Equal(cfg.Providers.Names, []string{"fake"}) || !slices.Equal(cfg.Providers.Pivots, []string{"USD"}) {
+		t.Errorf("unexpected providers: %+v", cfg.Providers)
+	}
+}
+
+func TestLoad_TomlFile(t *testing.T) {
+	path := writeFile(t, "server.toml", `
+[worker]
+queue_size = 50
+cache_ttl = "10s"
+
+[refresh]
+schedules = "EUR/USD=@every 1m"
+`)
+	cfg, err := load([]string{"-config", path}, env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Worker.QueueSize != 50 || cfg.Worker.CacheTTL != 10*time.Second || cfg.Refresh.Schedules != "EUR/USD=@every 1m" {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+}
+
+func TestLoad_RejectsUnknownSettings(t *testing.T) {
+	for _, file := range []struct{ name, content string }{
+		{"server.yaml", "worker:\n  pool_sise: 2\n"},
+		{"server.toml", "[worker]\npool_sise = 2\n"},
+	} {
+		path := writeFile(t, file.name, file.content)
+		if _, err := load([]string{"-config", path}, env(nil)); err == nil {
+			t.Errorf("expected an error for a misspelled setting in %s", file.name)
+		}
+	}
+}
+
+func TestLoad_ReportsEveryInvalidSetting(t *testing.T) {
+	_, err := load(
+		[]string{"-worker-pool-size", "0"},
+		env(map[string]string{
+			"ADMIN_TOKEN":           "secret",
+			"ADMIN_ADDRESS":         ":8080",
+			"DB_PORT":               "70000",
+			"RATE_AGGREGATION":      "median",
+			"CONSENSUS_MIN_SOURCES": "0",
+			"RATE_PROVIDER":         "frankfurter,ecb",
+			"TRIANGULATION_PIVOTS":  "USD,EURO",
+			"REFRESH_SCHEDULES":     "EUR/USD=@every soon",
+			"PUBLICATION_SCHEDULE":  "at four",
+			"RETRY_MAX_ATTEMPTS":    "0",
+			"RETRY_MAX_DELAY":       "1s",
+		}),
+	)
+	if err == nil {
+		t.Fatal("expected validation to fail")
+	}
+	for _, setting := range []string{
+		"server.admin_address", "worker.pool_size", "database.port", "providers.aggregation", "providers.consensus_min_sources",
+		`unknown provider "ecb"`, "providers.pivots", "refresh.schedules", "refresh.publication_schedule",
+		"retry.max_attempts", "retry.max_delay",
+	} {
+		if !strings.Contains(err.Error(), setting) {
+			t.Errorf("expected the error to mention %s, got %v", setting, err)
+		}
+	}
+
+	if _, err := load(nil, env(map[string]string{"CACHE_TTL": "soon"})); err == nil {
+		t.Error("expected an error for an invalid duration")
+	}
+	if _, err := load(nil, env(map[string]string{"CONSENSUS_DEVIATION": "1%"})); err == nil {
+		t.Error("expected an error for an invalid deviation")
+	}
+}
+
+func TestLoad_ConsensusOptions(t *testing.T) {
+	cfg, err := load(
+		[]string{"-consensus-min-sources", "3"},
+		env(map[string]string{"CONSENSUS_DEVIATION": "0.005"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := external.ProviderOptions{RatesFile: "rates.json", ConsensusDeviation: 0.005, ConsensusMinSources: 3}
+	if got := cfg.Providers.Options(); got != want {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestLoad_RetryPolicy(t *testing.T) {
+	path := writeFile(t, "server.yaml", "retry:\n  max_attempts: 6\n  base_delay: 500ms\n")
+	cfg, err := load(
+		[]string{"-config", path, "-retry-max-delay", "1m"},
+		env(map[string]string{"RETRY_BASE_DELAY": "1s"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := RetryConfig{MaxAttempts: 6, BaseDelay: time.Second, MaxDelay: time.Minute}
+	if cfg.Retry != want {
+		t.Fatalf("expected %+v, got %+v", want, cfg.Retry)
+	}
+}
+
+func TestDatabaseConfig_DSNEscapesCredentials(t *testing.T) {
+	cfg := Default().Database
+	cfg.Password = "p@ss word/"
+	if got := cfg.DSN(); got != "postgres://postgres:p%40ss%20word%2F@db:5432/esr?sslmode=disable" {
+		t.Fatalf("unexpected DSN %s", got)
+	}
+}
+
+func TestConfig_StringRedactsSecrets(t *testing.T) {
+	cfg := Default()
+	cfg.Database.Password = "db-secret"
+	cfg.Webhooks.Secret = "hook-secret"
+	cfg.Server.AdminToken = "admin-secret"
+
+	text := cfg.String()
+	if strings.Contains(text, "db-secret") || strings.Contains(text, "hook-secret") || strings.Contains(text, "admin-secret") {
+		t.Fatalf("expected secrets to be redacted:\n%s", text)
+	}
+	if !strings.Contains(text, "cache_ttl: 30s") {
+		t.Fatalf("expected durations to be readable:\n%s", text)
+	}
+	if cfg.Database.Password != "db-secret" {
+		t.Fatal("expected String not to change the config")
+	}
+}
+
+func TestConfig_NeedsRestart(t *testing.T) {
+	current := Default()
+
+	next := Default()
+	next.Worker.CacheTTL = time.Minute
+	next.Providers.Names = []string{"fake"}
+	next.Refresh.Schedules = "EUR/USD=@every 1m"
+	if settings := current.NeedsRestart(next); len(settings) != 0 {
+		t.Fatalf("expected reloadable settings only, got %v", settings)
+	}
+
+	next.Database.Host = "other"
+	next.Worker.PoolSize = 8
+	next.Providers.Pivots = []string{"GBP"}
+	next.Retry.MaxAttempts = 1
+	want := []string{"database", "worker.pool_size", "providers.pivots", "retry"}
+	if settings := current.NeedsRestart(next); !slices.Equal(settings, want) {
+		t.Fatalf("expected %v, got %v", want, settings)
+	}
+}
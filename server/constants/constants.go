@@ -3,8 +3,66 @@ package constants
 import "time"
 
 const (
-	CacheTTL                 = 30 * time.Second
-	WorkerQueueSize          = 200
+	DefaultCacheTTL          = 30 * time.Second
+	DefaultWorkerQueueSize   = 200
+	DefaultWorkerPoolSize    = 4
+	WorkerQueueWait          = 200 * time.Millisecond
+	QueueFullRetryAfter      = 5 * time.Second
+	RequestLeaseTime         = time.Minute
+	QueuePollInterval        = 2 * time.Second
+	QueueClaimBatch          = 50
+	DefaultDbHost            = "db"
+	DefaultDbPort            = 5432
+	DefaultDbUser            = "postgres"
+	DefaultDbPassword        = "postgres"
+	DefaultDbName            = "esr"
+	DefaultDbSSLMode         = "disable"
 	NumOfAttemptsToConnectDB = 10
 	PauseToWaitDBConnection  = 2 * time.Second
+	DefaultRateProviders     = "frankfurter,erapi"
+	DefaultRatesFilePath     = "rates.json"
+	ProviderFailureThreshold = 3
+	ProviderEjectionTime     = 1 * time.Minute
+	ProviderTimeout          = 10 * time.Second
+	RetryMaxAttempts         = 4
+	RetryBaseDelay           = 2 * time.Second
+	RetryMaxDelay            = 30 * time.Second
+	RetryJitter              = 0.2
+	DefaultRateAggregation   = "fallback"
+	DefaultPageLimit         = 100
+	MaxPageLimit             = 1000
+	DefaultCandleCount       = 100
+	MaxCandleCount           = 1000
+	DefaultPivotCurrencies   = "USD,EUR"
+	DefaultRoundingMode      = "half-even"
+	MaxAmountDigits          = 38
+	MaxAmountExponent        = 30
+	MaxBatchSize             = 100
+	EventReplaySize          = 1000
+	EventSubscriberBuffer    = 64
+	EventPollInterval        = 1 * time.Second
+	RequestPollInterval      = 2 * time.Second
+	StreamHeartbeatInterval  = 15 * time.Second
+	WebSocketWriteTimeout    = 10 * time.Second
+	WebSocketReadLimit       = 16 << 10
+	MaxFollowedRequests      = 100
+	WebhookTimeout           = 5 * time.Second
+	WebhookMaxAttempts       = 5
+	WebhookInitialBackoff    = 1 * time.Second
+	WebhookLeaseTime         = time.Minute
+	WebhookClaimBatch        = 20
+	WebhookPollInterval      = 2 * time.Second
+	DefaultGrpcAddress       = ":9090"
+	DefaultHttpAddress       = ":8080"
+	DefaultAdminAddress      = "localhost:8081"
+	DefaultShutdownTimeout   = 20 * time.Second
+	DefaultRefreshWait       = 5 * time.Second
+	MaxRefreshWait           = 30 * time.Second
+	// Shortest claim of a scheduled refresh, also used for the last tick of a schedule.
+	MinRefreshClaimHold = 5 * time.Second
+	// Defaults of the consensus aggregation: how far from the median an answer may be and how many are needed.
+	DefaultConsensusDeviation  = 0.01
+	DefaultConsensusMinSources = 2
+	// Frankfurter publishes the ECB reference rates once per business day, around 16:00 CET.
+	DefaultPublicationSchedule = "0 15 * * 1-5"
 )
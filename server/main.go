@@ -1,16 +1,31 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc"
 
 	// "encoding/json"
 
+	"github.com/artem98/ExchangeRateService/server/config"
 	"github.com/artem98/ExchangeRateService/server/constants"
 	"github.com/artem98/ExchangeRateService/server/rates/db"
+	"github.com/artem98/ExchangeRateService/server/rates/events"
+	"github.com/artem98/ExchangeRateService/server/rates/external"
+	"github.com/artem98/ExchangeRateService/server/rates/grpcapi"
 	"github.com/artem98/ExchangeRateService/server/rates/handlers"
+	"github.com/artem98/ExchangeRateService/server/rates/scheduler"
+	"github.com/artem98/ExchangeRateService/server/rates/webhooks"
 	"github.com/artem98/ExchangeRateService/server/rates/worker"
 )
 
@@ -18,24 +33,210 @@ func defaultHandler(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintln(w, "Hello client!")
 }
 
+// leaseOwner identifies this server process in the leases of update requests.
+func leaseOwner() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "server"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
+func refreshEntries(cfg config.RefreshConfig) ([]scheduler.Entry, scheduler.Schedule, error) {
+	entries, err := scheduler.ParseEntries(cfg.Schedules)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var publication scheduler.Schedule
+	if cfg.Publication != "none" {
+		if publication, err = scheduler.ParseSchedule(cfg.Publication); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, entry := range entries {
+		fmt.Printf("Refreshing %d pairs on schedule %q\n", len(entry.Pairs), entry.Spec)
+	}
+	return entries, publication, nil
+}
+
+// shutdown stops the servers and the worker within timeout. adminServer is nil
+// when the admin endpoints are disabled.
+// HTTP and gRPC calls in progress finish first, then the queued update jobs
+// get the rest of the time. Jobs that have not started by then give up the
+// leases of their requests, so another server picks them up without waiting
+// for the leases to expire. Jobs still running keep theirs.
+func shutdown(timeout time.Duration, httpServer, adminServer *http.Server, grpcServer *grpc.Server, jobsWorker *worker.Worker) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if adminServer != nil {
+		if err := adminServer.Shutdown(ctx); err != nil {
+			fmt.Println("Admin server did not stop in time:", err)
+		}
+	}
+
+	if err := httpServer.Shutdown(ctx); err != nil {
+		fmt.Println("HTTP server did not stop in time:", err)
+	}
+
+	grpcStopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
+	select {
+	case <-grpcStopped:
+	case <-ctx.Done():
+		grpcServer.Stop()
+	}
+
+	if left := jobsWorker.Stop(ctx); left > 0 {
+		fmt.Printf("Released %d update requests not processed before shutdown\n", left)
+	}
+}
+
 func main() {
-	dbAdapter, err := db.MakeDataBaseAdapter()
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	fmt.Printf("Effective configuration:\n%s", cfg)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// Caught before connecting to the database, so a SIGHUP sent during startup
+	// is applied once the server runs instead of ending it.
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+
+	chain, err := external.MakeProviderChain(cfg.Providers.Names, cfg.Providers.Aggregation, cfg.Providers.Options())
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	fmt.Println("Using rate provider", chain.Name())
+	provider := external.MakeReloadableProvider(chain)
+
+	dbAdapter, err := db.MakeDataBaseAdapter(cfg.Database.DSN(), provider)
 	if err != nil {
 		fmt.Println(err.Error())
 		return
 	}
 	defer dbAdapter.CloseDB()
+	// The loops polling the database return once ctx is done, and are waited
+	// for before it is closed.
+	var background sync.WaitGroup
+	inBackground := func(run func()) {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			run()
+		}()
+	}
+	defer func() {
+		stop()
+		background.Wait()
+	}()
 
+	broker, err := events.MakeBroker(dbAdapter, constants.EventReplaySize, constants.EventSubscriberBuffer)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	inBackground(func() { broker.Run(ctx, constants.EventPollInterval) })
+	owner := leaseOwner()
+	jobsWorker := worker.MakeWorker(cfg.Worker.PoolSize, cfg.Worker.QueueSize)
+	cache := worker.MakeRateJobsCache(cfg.Worker.CacheTTL)
+	retry := worker.MakeRetryPolicy(cfg.Retry.MaxAttempts, cfg.Retry.BaseDelay, cfg.Retry.MaxDelay)
+	shuttingDown := make(chan struct{})
 	ratesHandler := &handlers.Handler{
-		Db:     dbAdapter,
-		Worker: worker.MakeWorker(),
-		Cache:  worker.MakeRateJobsCache(constants.CacheTTL),
+		Db:         dbAdapter,
+		Worker:     jobsWorker,
+		Cache:      cache,
+		Provider:   provider,
+		Pivots:     cfg.Providers.Pivots,
+		Events:     broker,
+		LeaseOwner: owner,
+		Retry:      retry,
+		// Closed by the HTTP server once it starts to shut down.
+		ShuttingDown: shuttingDown,
+	}
+
+	fmt.Println("Processing update requests as", owner)
+	queue := worker.MakeQueue(owner, dbAdapter, jobsWorker, provider, broker, retry)
+	inBackground(func() { queue.Run(ctx) })
+
+	entries, publication, err := refreshEntries(cfg.Refresh)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	refresher := scheduler.MakeScheduler(entries, ratesHandler, dbAdapter, dbAdapter, publication)
+	inBackground(func() { refresher.Run(ctx) })
+
+	reload := makeReloader(os.Args[1:], cfg, cache, provider, refresher)
+	inBackground(func() { reload.watchHangups(ctx, hangups) })
+
+	if cfg.Webhooks.Secret != "" {
+		ratesHandler.CallbacksEnabled = true
+		dispatcher := webhooks.MakeDispatcher(dbAdapter, cfg.Webhooks.Secret)
+		inBackground(func() { dispatcher.Run(ctx, broker) })
+	} else {
+		fmt.Println("WEBHOOK_SECRET is not set, update requests with callback_url are rejected")
 	}
 
 	router := chi.NewRouter()
 	router.Route("/rates", ratesHandler.HandleRates)
+	router.Route("/convert", ratesHandler.HandleConvert)
+	router.Route("/ws", ratesHandler.HandleWebSocket)
 	router.HandleFunc("/", defaultHandler)
 
-	fmt.Println("Server started")
-	http.ListenAndServe(":8080", router)
+	var adminServer *http.Server
+	if cfg.Server.AdminToken != "" {
+		adminRouter := chi.NewRouter()
+		adminRouter.Post("/admin/reload", reload.handleReload)
+		adminServer = &http.Server{
+			Addr:    cfg.Server.AdminAddress,
+			Handler: adminRouter,
+		}
+		go func() {
+			fmt.Println("Admin server started on", cfg.Server.AdminAddress)
+			if err := adminServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				fmt.Println("Admin server stopped:", err)
+				stop()
+			}
+		}()
+	}
+
+	listener, err := net.Listen("tcp", cfg.Server.GrpcAddress)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	grpcServer := grpcapi.MakeServer(ratesHandler, grpcapi.CancelStreamsWith(ctx))
+	go func() {
+		fmt.Println("gRPC server started on", cfg.Server.GrpcAddress)
+		if err := grpcServer.Serve(listener); err != nil {
+			fmt.Println("gRPC server stopped:", err)
+		}
+	}()
+
+	httpServer := &http.Server{
+		Addr:    cfg.Server.HttpAddress,
+		Handler: router,
+	}
+	httpServer.RegisterOnShutdown(func() { close(shuttingDown) })
+	go func() {
+		fmt.Println("Server started")
+		if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			fmt.Println("HTTP server stopped:", err)
+			stop()
+		}
+	}()
+
+	<-ctx.Done()
+	fmt.Println("Shutting down")
+	shutdown(cfg.Server.ShutdownTimeout, httpServer, adminServer, grpcServer, jobsWorker)
 }
@@ -8,28 +8,141 @@ import (
 
 	"github.com/artem98/ExchangeRateService/server/constants"
 	"github.com/artem98/ExchangeRateService/server/rates/external"
-	_ "github.com/lib/pq"
+	"github.com/artem98/ExchangeRateService/server/rates/utils"
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
 )
 
 type DataBase interface {
-	GetRateByPair(cur1, cur2 string) (float64, time.Time, error)
-	GetRateByRequestId(id uint64) (float64, time.Time, error)
-	PlaceRequest(cur1, cur2 string) (uint64, error)
-	MarkRequestAsProcessed(requestId uint64) error
-	MarkRequestAsFailed(requestId uint64) error
-	UpdateRate(currency1, currency2 string, rate float64) error
+	GetRateByPair(cur1, cur2 string) (RateRecord, error)
+	GetRates(base string, pairs []CurrencyPair) ([]PairRateRecord, error)
+	GetUpdateRequest(id uint64) (UpdateRequestRecord, error)
+	GetUpdateRequests(ids []uint64) ([]UpdateRequestRecord, error)
+	PlaceRequest(cur1, cur2, callbackUrl, leaseOwner string) (uint64, error)
+	PlaceRequests(pairs []CurrencyPair, leaseOwner string) ([]uint64, error)
+	ClaimRequests(leaseOwner string, limit int) ([]UpdateRequestRecord, error)
+	RenewLease(requestId uint64, leaseOwner string) (bool, error)
+	ReleaseRequest(requestId uint64, leaseOwner string) error
+	RetryRequest(requestId uint64, leaseOwner, reason string, delay time.Duration) error
+	MarkRequestAsProcessed(requestId uint64, leaseOwner string, produced RateRecord) error
+	MarkRequestAsFailed(requestId uint64, leaseOwner, reason string) error
+	MarkRequestAsRejected(requestId uint64, leaseOwner, reason string) error
+	UpdateRate(currency1, currency2 string, quote external.Quote) (RateRecord, error)
+	GetRateHistory(currency1, currency2 string, from, to time.Time, limit, offset int) ([]RateRecord, error)
+	GetRateAsOf(currency1, currency2 string, asOf time.Time) (RateRecord, error)
+	GetRateCandles(currency1, currency2 string, interval time.Duration, from, to time.Time) ([]Candle, error)
+	RecordWebhookAttempt(attempt WebhookAttempt) error
+	ClaimWebhooks(limit int) ([]PendingWebhook, error)
+	RetryWebhook(requestId uint64, attempts int, delay time.Duration) error
+	FinishWebhook(requestId uint64, attempts int) error
+	ClaimRefresh(pair CurrencyPair, hold time.Duration) (bool, error)
+}
+
+type CurrencyPair struct {
+	Currency1 string
+	Currency2 string
+}
+
+// Candle summarizes the rate observations made within one time bucket.
+type Candle struct {
+	Start   time.Time
+	Open    decimal.Decimal
+	High    decimal.Decimal
+	Low     decimal.Decimal
+	Close   decimal.Decimal
+	Samples int
+}
+
+var (
+	ErrNoSuchPair    = errors.New("no such pair")
+	ErrNoRateAtTime  = errors.New("no rate observed at that time")
+	ErrNoSuchRequest = errors.New("no such update request")
+	// ErrLeaseLost is returned for a request that is finished or claimed by
+	// another server since its lease expired.
+	ErrLeaseLost = errors.New("update request is no longer leased")
+)
+
+type RateRecord struct {
+	Rate       decimal.Decimal
+	UpdateTime time.Time
+	Provider   string
+	Sources    []string
+	Spread     decimal.Decimal
+}
+
+type RequestStatus string
+
+const (
+	RequestSubmitted RequestStatus = "submitted"
+	RequestOk        RequestStatus = "ok"
+	RequestFailed    RequestStatus = "failed"
+	// RequestRejected requests were stored but never processed, because the
+	// worker queue was full.
+	RequestRejected RequestStatus = "rejected"
+)
+
+// UpdateRequestRecord tracks one update request from submission to completion.
+// CompletedAt is zero while the request is submitted, Rate is the quote the
+// request stored and is only set once it is ok. Attempts counts the fetches
+// made for the request and LastError is the error of the latest one that was
+// retried.
+type UpdateRequestRecord struct {
+	Id            uint64
+	Currency1     string
+	Currency2     string
+	Status        RequestStatus
+	CreatedAt     time.Time
+	CompletedAt   time.Time
+	FailureReason string
+	Rate          RateRecord
+	CallbackUrl   string
+	Attempts      int
+	LastError     string
+}
+
+// WebhookAttempt is one try to deliver the outcome of an update request to its
+// callback URL. StatusCode is zero when no response was received.
+type WebhookAttempt struct {
+	RequestId   uint64
+	CallbackUrl string
+	Attempt     int
+	AttemptedAt time.Time
+	StatusCode  int
+	Error       string
+}
+
+// PendingWebhook is a finished update request whose callback is due.
+// Attempts counts the deliveries tried so far.
+type PendingWebhook struct {
+	RequestId uint64
+	Attempts  int
+}
+
+// PairRateRecord is the latest stored rate of a pair.
+type PairRateRecord struct {
+	CurrencyPair
+	RateRecord
+}
+
+// RateChange is a rate stored in rate_history. Ids grow with every stored rate
+// and become visible in order.
+type RateChange struct {
+	Id uint64
+	CurrencyPair
+	RateRecord
 }
 
 type DataBaseAdapter struct {
 	database *sql.DB
+	provider external.RateProvider
 }
 
-func MakeDataBaseAdapter() (DataBaseAdapter, error) {
-	db, err := initDataBaseInterface()
+func MakeDataBaseAdapter(dsn string, provider external.RateProvider) (DataBaseAdapter, error) {
+	db, err := initDataBaseInterface(dsn)
 	if err != nil {
 		return DataBaseAdapter{}, err
 	}
-	a := DataBaseAdapter{database: db}
+	a := DataBaseAdapter{database: db, provider: provider}
 	err = a.fillRatesAtStart()
 	if err != nil {
 		return DataBaseAdapter{}, fmt.Errorf("failed to fill DB: %v", err)
@@ -38,9 +151,7 @@ func MakeDataBaseAdapter() (DataBaseAdapter, error) {
 	return a, nil
 }
 
-func initDataBaseInterface() (database *sql.DB, err error) {
-	dsn := "host=db port=5432 user=postgres password=postgres dbname=esr sslmode=disable"
-
+func initDataBaseInterface(dsn string) (database *sql.DB, err error) {
 	for i := 1; i <= constants.NumOfAttemptsToConnectDB; i++ {
 		database, err = sql.Open("postgres", dsn)
 		if err != nil {
@@ -82,7 +193,7 @@ func (a DataBaseAdapter) fillRatesAtStart() error {
 	fmt.Println("Start filling DB...")
 	for rows.Next() {
 		var currency1, currency2 string
-		var tableRate sql.NullFloat64
+		var tableRate decimal.NullDecimal
 		err := rows.Scan(&currency1, &currency2, &tableRate)
 		if err != nil {
 			return fmt.Errorf("failed to scan row: %w", err)
@@ -92,11 +203,11 @@ func (a DataBaseAdapter) fillRatesAtStart() error {
 			continue
 		}
 
-		rate, err := external.FetchRate(currency1, currency2)
+		quote, err := a.provider.FetchRate(currency1, currency2)
 		if err != nil {
 			return err
 		}
-		err = a.UpdateRate(currency1, currency2, rate)
+		_, err = a.UpdateRate(currency1, currency2, quote)
 
 		if err != nil {
 			return err
@@ -107,7 +218,9 @@ func (a DataBaseAdapter) fillRatesAtStart() error {
 	return nil
 }
 
-func (a DataBaseAdapter) PlaceRequest(currency1, currency2 string) (uint64, error) {
+// PlaceRequest inserts a submitted request leased to leaseOwner, so no other
+// server claims it while the placing one plans it.
+func (a DataBaseAdapter) PlaceRequest(currency1, currency2, callbackUrl, leaseOwner string) (uint64, error) {
 	var id uint64
 
 	if a.database == nil {
@@ -115,11 +228,12 @@ func (a DataBaseAdapter) PlaceRequest(currency1, currency2 string) (uint64, erro
 	}
 
 	query := `
-        INSERT INTO update_requests (currency1, currency2, request_status)
-        VALUES ($1, $2, $3)
+        INSERT INTO update_requests (currency1, currency2, request_status, callback_url, lease_owner, lease_expires_at)
+        VALUES ($1, $2, $3, NULLIF($4, ''), $5, now() + $6 * interval '1 millisecond')
         RETURNING id;
     `
-	err := a.database.QueryRow(query, currency1, currency2, "submitted").Scan(&id)
+	err := a.database.QueryRow(query, currency1, currency2, RequestSubmitted, callbackUrl,
+		leaseOwner, constants.RequestLeaseTime.Milliseconds()).Scan(&id)
 	if err != nil {
 		return 0, fmt.Errorf("failed to insert rate: %w", err)
 	}
@@ -127,92 +241,703 @@ func (a DataBaseAdapter) PlaceRequest(currency1, currency2 string) (uint64, erro
 	return id, nil
 }
 
-func (a DataBaseAdapter) GetRateByPair(currency1, currency2 string) (float64, time.Time, error) {
+// PlaceRequests inserts an update request for every pair in one transaction and
+// returns their ids in the same order. Like PlaceRequest, they are leased to leaseOwner.
+func (a DataBaseAdapter) PlaceRequests(pairs []CurrencyPair, leaseOwner string) ([]uint64, error) {
+	if a.database == nil {
+		return nil, fmt.Errorf("database is not initialized yet")
+	}
+
+	tx, err := a.database.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+        INSERT INTO update_requests (currency1, currency2, request_status, lease_owner, lease_expires_at)
+        VALUES ($1, $2, $3, $4, now() + $5 * interval '1 millisecond')
+        RETURNING id;
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare insert: %w", err)
+	}
+	defer stmt.Close()
+
+	ids := make([]uint64, len(pairs))
+	for i, pair := range pairs {
+		err := stmt.QueryRow(pair.Currency1, pair.Currency2, RequestSubmitted,
+			leaseOwner, constants.RequestLeaseTime.Milliseconds()).Scan(&ids[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert request for %s/%s: %w", pair.Currency1, pair.Currency2, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit requests: %w", err)
+	}
+	return ids, nil
+}
+
+// ClaimRequests leases up to limit submitted requests nobody holds a lease on,
+// oldest first. Rows locked by another claim are skipped rather than waited
+// for, and requests of pairs another server is updating are left for later.
+func (a DataBaseAdapter) ClaimRequests(leaseOwner string, limit int) ([]UpdateRequestRecord, error) {
+	if a.database == nil {
+		return nil, errors.New("database not initialized")
+	}
+
+	query := `
+        UPDATE update_requests
+        SET lease_owner = $1, lease_expires_at = now() + $3 * interval '1 millisecond'
+        WHERE id IN (
+            SELECT r.id FROM update_requests r
+            WHERE r.request_status = $4
+              AND (r.lease_expires_at IS NULL OR r.lease_expires_at < now())
+              AND NOT EXISTS (
+                  SELECT 1 FROM update_requests l
+                  WHERE l.currency1 = r.currency1 AND l.currency2 = r.currency2
+                    AND l.request_status = $4 AND l.lease_owner <> $1 AND l.lease_expires_at >= now()
+              )
+            ORDER BY r.id
+            LIMIT $2
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING id, currency1, currency2, request_status, created_at, COALESCE(callback_url, ''),
+                  attempts, COALESCE(last_error, '')
+    `
+	rows, err := a.database.Query(query, leaseOwner, limit, constants.RequestLeaseTime.Milliseconds(), RequestSubmitted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim requests: %w", err)
+	}
+	defer rows.Close()
+
+	var records []UpdateRequestRecord
+	for rows.Next() {
+		var record UpdateRequestRecord
+		err := rows.Scan(&record.Id, &record.Currency1, &record.Currency2, &record.Status, &record.CreatedAt, &record.CallbackUrl,
+			&record.Attempts, &record.LastError)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan request: %w", err)
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read requests: %w", err)
+	}
+	return records, nil
+}
+
+// RenewLease extends the lease of leaseOwner on a submitted request. It reports
+// false when the request is finished or has been claimed by another server.
+func (a DataBaseAdapter) RenewLease(requestId uint64, leaseOwner string) (bool, error) {
+	if a.database == nil {
+		return false, errors.New("database not initialized")
+	}
+
+	query := `
+        UPDATE update_requests
+        SET lease_expires_at = now() + $3 * interval '1 millisecond'
+        WHERE id = $1 AND lease_owner = $2 AND request_status = $4
+    `
+	result, err := a.database.Exec(query, requestId, leaseOwner, constants.RequestLeaseTime.Milliseconds(), RequestSubmitted)
+	if err != nil {
+		return false, fmt.Errorf("failed to renew lease: %w", err)
+	}
+	renewed, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to renew lease: %w", err)
+	}
+	return renewed == 1, nil
+}
+
+// ReleaseRequest gives up the lease of leaseOwner, so the request can be claimed again right away.
+func (a DataBaseAdapter) ReleaseRequest(requestId uint64, leaseOwner string) error {
+	if a.database == nil {
+		return errors.New("database not initialized")
+	}
+
+	query := `
+        UPDATE update_requests
+        SET lease_owner = NULL, lease_expires_at = NULL
+        WHERE id = $1 AND lease_owner = $2
+    `
+	_, err := a.database.Exec(query, requestId, leaseOwner)
+	if err != nil {
+		return fmt.Errorf("failed to release request: %w", err)
+	}
+	return nil
+}
+
+// RetryRequest counts a failed attempt of leaseOwner and gives up its lease
+// until delay has passed, after which any server may claim the request again.
+func (a DataBaseAdapter) RetryRequest(requestId uint64, leaseOwner, reason string, delay time.Duration) error {
+	if a.database == nil {
+		return errors.New("database not initialized")
+	}
+
+	query := `
+        UPDATE update_requests
+        SET attempts = attempts + 1, last_error = $3,
+            lease_owner = NULL, lease_expires_at = now() + $4 * interval '1 millisecond'
+        WHERE id = $1 AND lease_owner = $2 AND request_status = $5
+    `
+	result, err := a.database.Exec(query, requestId, leaseOwner, reason, delay.Milliseconds(), RequestSubmitted)
+	if err != nil {
+		return fmt.Errorf("failed to schedule retry: %w", err)
+	}
+	if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+		return fmt.Errorf("failed to schedule retry of request %d: %w", requestId, ErrLeaseLost)
+	}
+	return nil
+}
+
+func (a DataBaseAdapter) GetRateByPair(currency1, currency2 string) (RateRecord, error) {
 	if a.database == nil {
-		return 0, time.Time{}, errors.New("database not initialized")
+		return RateRecord{}, errors.New("database not initialized")
 	}
 
-	var rate float64
-	var timestamp time.Time
+	var record RateRecord
 	query := `
-        SELECT rate, update_time FROM rates
-        WHERE currency1 = $1 AND currency2 = $2
+        SELECT rate, update_time, COALESCE(provider, ''), COALESCE(sources, '{}'), COALESCE(spread, 0) FROM rates
+        WHERE currency1 = $1 AND currency2 = $2 AND rate IS NOT NULL
         LIMIT 1
     `
-	err := a.database.QueryRow(query, currency1, currency2).Scan(&rate, &timestamp)
+	err := a.database.QueryRow(query, currency1, currency2).Scan(
+		&record.Rate, &record.UpdateTime, &record.Provider, pq.Array(&record.Sources), &record.Spread)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return 0, time.Time{}, errors.New("no such pair")
+			return RateRecord{}, ErrNoSuchPair
 		}
-		return 0, time.Time{}, fmt.Errorf("db query error: %w", err)
+		return RateRecord{}, fmt.Errorf("db query error: %w", err)
 	}
 
-	return rate, timestamp, nil
+	return record, nil
 }
 
-func (a DataBaseAdapter) GetRateByRequestId(requestId uint64) (float64, time.Time, error) {
+// GetRates returns the latest rate of every stored pair ordered by pair. Rates are
+// limited to pairs quoted in base when it is not empty, and to the given pairs
+// when there are any.
+func (a DataBaseAdapter) GetRates(base string, pairs []CurrencyPair) ([]PairRateRecord, error) {
 	if a.database == nil {
-		return 0, time.Time{}, errors.New("database not initialized")
+		return nil, errors.New("database not initialized")
 	}
 
-	var currency1, currency2 string
-	err := a.database.QueryRow(`
-        SELECT currency1, currency2 FROM update_requests
-        WHERE id = $1
-    `, requestId).Scan(&currency1, &currency2)
+	currencies1 := make([]string, len(pairs))
+	currencies2 := make([]string, len(pairs))
+	for i, pair := range pairs {
+		currencies1[i], currencies2[i] = pair.Currency1, pair.Currency2
+	}
+
+	query := `
+        SELECT currency1, currency2, rate, update_time, COALESCE(provider, ''), COALESCE(sources, '{}'), COALESCE(spread, 0)
+        FROM rates
+        WHERE rate IS NOT NULL
+          AND ($1 = '' OR currency1 = $1)
+          AND (cardinality($2::text[]) = 0 OR (currency1, currency2) IN (SELECT * FROM unnest($2::text[], $3::text[])))
+        ORDER BY currency1, currency2
+    `
+	rows, err := a.database.Query(query, base, pq.Array(currencies1), pq.Array(currencies2))
+	if err != nil {
+		return nil, fmt.Errorf("db query error: %w", err)
+	}
+	defer rows.Close()
+
+	var records []PairRateRecord
+	for rows.Next() {
+		var record PairRateRecord
+		err := rows.Scan(&record.Currency1, &record.Currency2, &record.Rate, &record.UpdateTime,
+			&record.Provider, pq.Array(&record.Sources), &record.Spread)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("db query error: %w", err)
+	}
+
+	return records, nil
+}
+
+const updateRequestColumns = `
+        id, currency1, currency2, request_status, created_at, completed_at, COALESCE(failure_reason, ''),
+        rate, update_time, COALESCE(provider, ''), COALESCE(sources, '{}'), COALESCE(spread, 0),
+        COALESCE(callback_url, ''), attempts, COALESCE(last_error, '')
+    `
+
+func scanUpdateRequest(row interface{ Scan(dest ...any) error }) (UpdateRequestRecord, error) {
+	var record UpdateRequestRecord
+	var completedAt, updateTime sql.NullTime
+	var rate decimal.NullDecimal
+	err := row.Scan(
+		&record.Id, &record.Currency1, &record.Currency2, &record.Status, &record.CreatedAt, &completedAt,
+		&record.FailureReason, &rate, &updateTime, &record.Rate.Provider, pq.Array(&record.Rate.Sources), &record.Rate.Spread,
+		&record.CallbackUrl, &record.Attempts, &record.LastError)
+	if err != nil {
+		return UpdateRequestRecord{}, err
+	}
+
+	record.CompletedAt = completedAt.Time
+	record.Rate.Rate = rate.Decimal
+	record.Rate.UpdateTime = updateTime.Time
+	return record, nil
+}
+
+func (a DataBaseAdapter) GetUpdateRequest(requestId uint64) (UpdateRequestRecord, error) {
+	if a.database == nil {
+		return UpdateRequestRecord{}, errors.New("database not initialized")
+	}
+
+	query := `SELECT ` + updateRequestColumns + ` FROM update_requests WHERE id = $1`
+	record, err := scanUpdateRequest(a.database.QueryRow(query, requestId))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return 0, time.Time{}, fmt.Errorf("no request with id %d", requestId)
+			return UpdateRequestRecord{}, ErrNoSuchRequest
 		}
-		return 0, time.Time{}, fmt.Errorf("failed to query request: %w", err)
+		return UpdateRequestRecord{}, fmt.Errorf("failed to query request: %w", err)
+	}
+	return record, nil
+}
+
+// GetUpdateRequests reads the given requests in one query. Ids with no request
+// are left out of the result.
+func (a DataBaseAdapter) GetUpdateRequests(requestIds []uint64) ([]UpdateRequestRecord, error) {
+	if a.database == nil {
+		return nil, errors.New("database not initialized")
 	}
 
-	return a.GetRateByPair(currency1, currency2)
+	ids := make([]int64, len(requestIds))
+	for i, id := range requestIds {
+		ids[i] = int64(id)
+	}
+	query := `SELECT ` + updateRequestColumns + ` FROM update_requests WHERE id = ANY($1)`
+	rows, err := a.database.Query(query, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query requests: %w", err)
+	}
+	defer rows.Close()
+
+	var records []UpdateRequestRecord
+	for rows.Next() {
+		record, err := scanUpdateRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan request: %w", err)
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read requests: %w", err)
+	}
+	return records, nil
 }
 
-func (a DataBaseAdapter) MarkRequestAsProcessed(requestId uint64) error {
+// MarkRequestAsProcessed completes the request leased to leaseOwner and keeps
+// the rate it produced, so it can still be reported after the pair has been
+// updated again. Like the other ways to finish a request, it makes its
+// callback due, and it fails with ErrLeaseLost once the lease is gone.
+func (a DataBaseAdapter) MarkRequestAsProcessed(requestId uint64, leaseOwner string, produced RateRecord) error {
 	if a.database == nil {
 		return fmt.Errorf("database not initialized")
 	}
 
-	query := `UPDATE update_requests SET request_status = 'ok' WHERE id = $1`
-	_, err := a.database.Exec(query, requestId)
+	query := `
+        UPDATE update_requests
+        SET request_status = $2, completed_at = now(), attempts = attempts + 1,
+            rate = $3, update_time = $4, provider = $5, sources = $6, spread = $7,
+            webhook_due_at = CASE WHEN callback_url IS NOT NULL THEN now() END
+        WHERE id = $1 AND lease_owner = $8 AND request_status = $9
+    `
+	result, err := a.database.Exec(query, requestId, RequestOk, utils.FormatDecimal(produced.Rate), produced.UpdateTime,
+		produced.Provider, pq.Array(produced.Sources), utils.FormatDecimal(produced.Spread), leaseOwner, RequestSubmitted)
 	if err != nil {
 		return fmt.Errorf("failed to mark request as processed: %w", err)
 	}
+	if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+		return fmt.Errorf("failed to mark request %d as processed: %w", requestId, ErrLeaseLost)
+	}
 	return nil
 }
 
-func (a DataBaseAdapter) MarkRequestAsFailed(requestId uint64) error {
+func (a DataBaseAdapter) MarkRequestAsFailed(requestId uint64, leaseOwner, reason string) error {
+	return a.markRequestAsUnsuccessful(requestId, leaseOwner, RequestFailed, reason, 1)
+}
+
+// MarkRequestAsRejected finishes a request nothing was attempted for.
+func (a DataBaseAdapter) MarkRequestAsRejected(requestId uint64, leaseOwner, reason string) error {
+	return a.markRequestAsUnsuccessful(requestId, leaseOwner, RequestRejected, reason, 0)
+}
+
+func (a DataBaseAdapter) markRequestAsUnsuccessful(requestId uint64, leaseOwner string, status RequestStatus, reason string, attempts int) error {
 	if a.database == nil {
 		return fmt.Errorf("database not initialized")
 	}
 
-	query := `UPDATE update_requests SET request_status = 'failed' WHERE id = $1`
-	_, err := a.database.Exec(query, requestId)
+	query := `
+        UPDATE update_requests
+        SET request_status = $2, completed_at = now(), failure_reason = $3, attempts = attempts + $4,
+            webhook_due_at = CASE WHEN callback_url IS NOT NULL THEN now() END
+        WHERE id = $1 AND lease_owner = $5 AND request_status = $6
+    `
+	result, err := a.database.Exec(query, requestId, status, reason, attempts, leaseOwner, RequestSubmitted)
 	if err != nil {
-		return fmt.Errorf("failed to mark request as failed: %w", err)
+		return fmt.Errorf("failed to mark request as %s: %w", status, err)
+	}
+	if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+		return fmt.Errorf("failed to mark request %d as %s: %w", requestId, status, ErrLeaseLost)
 	}
 	return nil
 }
 
-func (a DataBaseAdapter) UpdateRate(currency1, currency2 string, rate float64) error {
+func (a DataBaseAdapter) RecordWebhookAttempt(attempt WebhookAttempt) error {
 	if a.database == nil {
 		return fmt.Errorf("database not initialized")
 	}
 
 	query := `
-        INSERT INTO rates (currency1, currency2, rate, update_time)
-        VALUES ($1, $2, $3, $4)
+        INSERT INTO webhook_attempts (request_id, callback_url, attempt, attempted_at, status_code, error)
+        VALUES ($1, $2, $3, $4, NULLIF($5, 0), NULLIF($6, ''))
+    `
+	_, err := a.database.Exec(query, attempt.RequestId, attempt.CallbackUrl, attempt.Attempt, attempt.AttemptedAt,
+		attempt.StatusCode, attempt.Error)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook attempt: %w", err)
+	}
+	return nil
+}
+
+// ClaimWebhooks takes up to limit due callbacks, oldest first, and postpones
+// them by the webhook lease, so that no other server delivers them meanwhile
+// and they become due again if this one stops before finishing them.
+func (a DataBaseAdapter) ClaimWebhooks(limit int) ([]PendingWebhook, error) {
+	if a.database == nil {
+		return nil, errors.New("database not initialized")
+	}
+
+	query := `
+        UPDATE update_requests
+        SET webhook_due_at = now() + $2 * interval '1 millisecond'
+        WHERE id IN (
+            SELECT id FROM update_requests
+            WHERE webhook_due_at <= now()
+            ORDER BY webhook_due_at
+            LIMIT $1
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING id, webhook_attempts
+    `
+	rows, err := a.database.Query(query, limit, constants.WebhookLeaseTime.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	var webhooks []PendingWebhook
+	for rows.Next() {
+		var webhook PendingWebhook
+		if err := rows.Scan(&webhook.RequestId, &webhook.Attempts); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+// RetryWebhook stores the number of deliveries tried and makes the callback due
+// again after delay.
+func (a DataBaseAdapter) RetryWebhook(requestId uint64, attempts int, delay time.Duration) error {
+	if a.database == nil {
+		return errors.New("database not initialized")
+	}
+
+	query := `
+        UPDATE update_requests
+        SET webhook_attempts = $2, webhook_due_at = now() + $3 * interval '1 millisecond'
+        WHERE id = $1
+    `
+	if _, err := a.database.Exec(query, requestId, attempts, delay.Milliseconds()); err != nil {
+		return fmt.Errorf("failed to schedule webhook retry: %w", err)
+	}
+	return nil
+}
+
+// FinishWebhook stores the number of deliveries tried and drops the callback
+// from the due ones, after it was delivered or given up on.
+func (a DataBaseAdapter) FinishWebhook(requestId uint64, attempts int) error {
+	if a.database == nil {
+		return errors.New("database not initialized")
+	}
+
+	query := `
+        UPDATE update_requests
+        SET webhook_attempts = $2, webhook_due_at = NULL
+        WHERE id = $1
+    `
+	if _, err := a.database.Exec(query, requestId, attempts); err != nil {
+		return fmt.Errorf("failed to finish webhook: %w", err)
+	}
+	return nil
+}
+
+// ClaimRefresh reports whether the scheduled refresh of pair is left to this
+// server. The first server to claim the pair keeps it for hold, claims of the
+// others fail meanwhile.
+func (a DataBaseAdapter) ClaimRefresh(pair CurrencyPair, hold time.Duration) (bool, error) {
+	if a.database == nil {
+		return false, errors.New("database not initialized")
+	}
+
+	query := `
+        INSERT INTO scheduled_refreshes (currency1, currency2, claimed_until)
+        VALUES ($1, $2, now() + $3 * interval '1 millisecond')
+        ON CONFLICT (currency1, currency2) DO UPDATE SET claimed_until = EXCLUDED.claimed_until
+        WHERE scheduled_refreshes.claimed_until <= now()
+    `
+	result, err := a.database.Exec(query, pair.Currency1, pair.Currency2, hold.Milliseconds())
+	if err != nil {
+		return false, fmt.Errorf("failed to claim scheduled refresh: %w", err)
+	}
+	claimed, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim scheduled refresh: %w", err)
+	}
+	return claimed == 1, nil
+}
+
+// UpdateRate stores the quote as the latest rate of the pair and returns the stored record.
+func (a DataBaseAdapter) UpdateRate(currency1, currency2 string, quote external.Quote) (RateRecord, error) {
+	if a.database == nil {
+		return RateRecord{}, fmt.Errorf("database not initialized")
+	}
+
+	tx, err := a.database.Begin()
+	if err != nil {
+		return RateRecord{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Held until the commit, so rates are committed in the order of their
+	// history ids and readers of RateChanges after an id never skip one.
+	_, err = tx.Exec("LOCK TABLE rate_history IN SHARE ROW EXCLUSIVE MODE")
+	if err != nil {
+		return RateRecord{}, fmt.Errorf("failed to lock rate history: %w", err)
+	}
+
+	// The column has no time zone, the offset of a local time would be dropped.
+	updateTime := time.Now().UTC()
+
+	query := `
+        INSERT INTO rates (currency1, currency2, rate, provider, sources, spread, update_time)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
         ON CONFLICT (currency1, currency2) DO UPDATE
         SET rate = EXCLUDED.rate,
+            provider = EXCLUDED.provider,
+            sources = EXCLUDED.sources,
+            spread = EXCLUDED.spread,
             update_time = EXCLUDED.update_time;
     `
+	rate, spread := utils.FormatDecimal(quote.Rate), utils.FormatDecimal(quote.Spread)
+	_, err = tx.Exec(query, currency1, currency2, rate, quote.Provider, pq.Array(quote.Sources), spread, updateTime)
+	if err != nil {
+		return RateRecord{}, fmt.Errorf("failed to upsert rate: %w", err)
+	}
 
-	_, err := a.database.Exec(query, currency1, currency2, rate, time.Now())
+	query = `
+        INSERT INTO rate_history (currency1, currency2, rate, provider, sources, spread, update_time)
+        VALUES ($1, $2, $3, $4, $5, $6, $7);
+    `
+	_, err = tx.Exec(query, currency1, currency2, rate, quote.Provider, pq.Array(quote.Sources), spread, updateTime)
 	if err != nil {
-		return fmt.Errorf("failed to upsert rate: %w", err)
+		return RateRecord{}, fmt.Errorf("failed to append rate history: %w", err)
 	}
 
-	return nil
+	if err = tx.Commit(); err != nil {
+		return RateRecord{}, fmt.Errorf("failed to commit rate update: %w", err)
+	}
+	return RateRecord{
+		Rate:       quote.Rate,
+		UpdateTime: updateTime,
+		Provider:   quote.Provider,
+		Sources:    quote.Sources,
+		Spread:     quote.Spread,
+	}, nil
+}
+
+// LastRateChangeId returns the id of the latest stored rate, 0 when there is none.
+func (a DataBaseAdapter) LastRateChangeId() (uint64, error) {
+	if a.database == nil {
+		return 0, errors.New("database not initialized")
+	}
+
+	var id uint64
+	err := a.database.QueryRow("SELECT COALESCE(MAX(id), 0) FROM rate_history").Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("db query error: %w", err)
+	}
+	return id, nil
+}
+
+// GetRateChanges returns up to limit rates stored after afterId, oldest first.
+func (a DataBaseAdapter) GetRateChanges(afterId uint64, limit int) ([]RateChange, error) {
+	query := `
+        SELECT id, currency1, currency2, rate, update_time, COALESCE(provider, ''), COALESCE(sources, '{}'), COALESCE(spread, 0)
+        FROM rate_history
+        WHERE id > $1
+        ORDER BY id
+        LIMIT $2
+    `
+	return a.queryRateChanges(query, afterId, limit)
+}
+
+// GetLatestRateChanges returns the latest limit rates of the pairs, or of all
+// pairs when there are none, stored after afterId and up to upToId, oldest first.
+func (a DataBaseAdapter) GetLatestRateChanges(pairs []CurrencyPair, afterId, upToId uint64, limit int) ([]RateChange, error) {
+	codes := make([]string, len(pairs))
+	for i, pair := range pairs {
+		codes[i] = pair.Currency1 + "/" + pair.Currency2
+	}
+
+	query := `
+        SELECT * FROM (
+            SELECT id, currency1, currency2, rate, update_time, COALESCE(provider, ''), COALESCE(sources, '{}'), COALESCE(spread, 0)
+            FROM rate_history
+            WHERE id > $1 AND id <= $2 AND (cardinality($3::text[]) = 0 OR currency1 || '/' || currency2 = ANY($3))
+            ORDER BY id DESC
+            LIMIT $4
+        ) latest
+        ORDER BY id
+    `
+	return a.queryRateChanges(query, afterId, upToId, pq.Array(codes), limit)
+}
+
+func (a DataBaseAdapter) queryRateChanges(query string, args ...any) ([]RateChange, error) {
+	if a.database == nil {
+		return nil, errors.New("database not initialized")
+	}
+
+	rows, err := a.database.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("db query error: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []RateChange
+	for rows.Next() {
+		var c RateChange
+		err := rows.Scan(&c.Id, &c.Currency1, &c.Currency2,
+			&c.Rate, &c.UpdateTime, &c.Provider, pq.Array(&c.Sources), &c.Spread)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		changes = append(changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("db query error: %w", err)
+	}
+
+	return changes, nil
+}
+
+// GetRateAsOf returns the latest observation of the pair made at or before asOf.
+func (a DataBaseAdapter) GetRateAsOf(currency1, currency2 string, asOf time.Time) (RateRecord, error) {
+	if a.database == nil {
+		return RateRecord{}, errors.New("database not initialized")
+	}
+
+	var record RateRecord
+	query := `
+        SELECT rate, update_time, COALESCE(provider, ''), COALESCE(sources, '{}'), COALESCE(spread, 0)
+        FROM rate_history
+        WHERE currency1 = $1 AND currency2 = $2 AND update_time <= $3
+        ORDER BY update_time DESC, id DESC
+        LIMIT 1
+    `
+	err := a.database.QueryRow(query, currency1, currency2, asOf).Scan(
+		&record.Rate, &record.UpdateTime, &record.Provider, pq.Array(&record.Sources), &record.Spread)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return RateRecord{}, ErrNoRateAtTime
+		}
+		return RateRecord{}, fmt.Errorf("db query error: %w", err)
+	}
+
+	return record, nil
+}
+
+func (a DataBaseAdapter) GetRateHistory(currency1, currency2 string, from, to time.Time, limit, offset int) ([]RateRecord, error) {
+	if a.database == nil {
+		return nil, errors.New("database not initialized")
+	}
+
+	query := `
+        SELECT rate, update_time, COALESCE(provider, ''), COALESCE(sources, '{}'), COALESCE(spread, 0)
+        FROM rate_history
+        WHERE currency1 = $1 AND currency2 = $2 AND update_time >= $3 AND update_time < $4
+        ORDER BY update_time, id
+        LIMIT $5 OFFSET $6
+    `
+	rows, err := a.database.Query(query, currency1, currency2, from, to, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("db query error: %w", err)
+	}
+	defer rows.Close()
+
+	var records []RateRecord
+	for rows.Next() {
+		var record RateRecord
+		err := rows.Scan(&record.Rate, &record.UpdateTime, &record.Provider, pq.Array(&record.Sources), &record.Spread)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("db query error: %w", err)
+	}
+
+	return records, nil
+}
+
+// GetRateCandles buckets the observations of the pair made within [from, to) into
+// intervals aligned to 2000-01-01 UTC and returns the non-empty buckets in order.
+func (a DataBaseAdapter) GetRateCandles(currency1, currency2 string, interval time.Duration, from, to time.Time) ([]Candle, error) {
+	if a.database == nil {
+		return nil, errors.New("database not initialized")
+	}
+
+	query := `
+        SELECT date_bin(make_interval(secs => $3), update_time, TIMESTAMP '2000-01-01') AS bucket,
+               (array_agg(rate ORDER BY update_time, id))[1],
+               MAX(rate),
+               MIN(rate),
+               (array_agg(rate ORDER BY update_time DESC, id DESC))[1],
+               COUNT(*)
+        FROM rate_history
+        WHERE currency1 = $1 AND currency2 = $2 AND update_time >= $4 AND update_time < $5
+        GROUP BY bucket
+        ORDER BY bucket
+    `
+	rows, err := a.database.Query(query, currency1, currency2, interval.Seconds(), from, to)
+	if err != nil {
+		return nil, fmt.Errorf("db query error: %w", err)
+	}
+	defer rows.Close()
+
+	var candles []Candle
+	for rows.Next() {
+		var c Candle
+		err := rows.Scan(&c.Start, &c.Open, &c.High, &c.Low, &c.Close, &c.Samples)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		candles = append(candles, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("db query error: %w", err)
+	}
+
+	return candles, nil
 }
@@ -0,0 +1,245 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/artem98/ExchangeRateService/server/rates/db"
+)
+
+// RateEvent announces a new stored rate of a pair. Id is the id of the rate in
+// rate_history, so it is the same on every server and survives restarts.
+type RateEvent struct {
+	Id uint64
+	db.CurrencyPair
+	Record db.RateRecord
+}
+
+// RequestEvent announces that an update request has finished.
+type RequestEvent struct {
+	RequestId uint64
+	db.CurrencyPair
+	Status db.RequestStatus
+}
+
+// Store reads back the stored rates, which are the source of the rate events.
+type Store interface {
+	LastRateChangeId() (uint64, error)
+	GetRateChanges(afterId uint64, limit int) ([]db.RateChange, error)
+	GetLatestRateChanges(pairs []db.CurrencyPair, afterId, upToId uint64, limit int) ([]db.RateChange, error)
+}
+
+// Subscription delivers the events of the subscribed pairs on C. C is closed
+// when the subscriber falls too far behind, it can subscribe again with the
+// id of the last event it received. After is the id the events on C follow.
+type Subscription struct {
+	C     <-chan RateEvent
+	After uint64
+	ch    chan RateEvent
+	pairs map[db.CurrencyPair]bool
+}
+
+func (s *Subscription) wants(event RateEvent) bool {
+	return event.Id > s.After && (len(s.pairs) == 0 || s.pairs[event.CurrencyPair])
+}
+
+// RequestSubscription delivers every request event on C. Like Subscription it
+// is closed when the subscriber falls behind, the finished requests can be
+// read back from the database.
+type RequestSubscription struct {
+	C  <-chan RequestEvent
+	ch chan RequestEvent
+}
+
+// Broker fans rate events out to subscribers. The events are read from the
+// rate history, so they include the rates stored by other servers sharing the
+// database, and reconnecting subscribers resume from it without gaps. Request
+// events are only delivered live, to subscribers of the server finishing them.
+type Broker struct {
+	store              Store
+	polling            sync.Mutex
+	stored             chan struct{}
+	mu                 sync.Mutex
+	lastId             uint64
+	replaySize         int
+	bufferSize         int
+	subscribers        map[*Subscription]struct{}
+	requestSubscribers map[*RequestSubscription]struct{}
+}
+
+// MakeBroker makes a broker delivering the rates stored from now on. At most
+// replaySize of the missed events are replayed to a subscriber.
+func MakeBroker(store Store, replaySize, bufferSize int) (*Broker, error) {
+	lastId, err := store.LastRateChangeId()
+	if err != nil {
+		return nil, err
+	}
+	return &Broker{
+		store:              store,
+		stored:             make(chan struct{}, 1),
+		lastId:             lastId,
+		replaySize:         replaySize,
+		bufferSize:         bufferSize,
+		subscribers:        make(map[*Subscription]struct{}),
+		requestSubscribers: make(map[*RequestSubscription]struct{}),
+	}, nil
+}
+
+// Run polls the store for new rates every interval, and right away when
+// RatesStored is called, until ctx is done.
+func (b *Broker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-b.stored:
+		}
+		if err := b.Poll(); err != nil {
+			fmt.Println("Failed to read rate events:", err)
+		}
+	}
+}
+
+// RatesStored tells the broker that this server has stored rates, so they are
+// delivered without waiting for the next poll.
+func (b *Broker) RatesStored() {
+	select {
+	case b.stored <- struct{}{}:
+	default:
+	}
+}
+
+// Poll delivers the rates stored since the last poll.
+func (b *Broker) Poll() error {
+	b.polling.Lock()
+	defer b.polling.Unlock()
+
+	for {
+		b.mu.Lock()
+		lastId := b.lastId
+		b.mu.Unlock()
+
+		changes, err := b.store.GetRateChanges(lastId, b.replaySize)
+		if err != nil {
+			return err
+		}
+		b.publish(changes)
+		if len(changes) < b.replaySize {
+			return nil
+		}
+	}
+}
+
+func (b *Broker) publish(changes []db.RateChange) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, change := range changes {
+		event := makeRateEvent(change)
+		b.lastId = event.Id
+		for s := range b.subscribers {
+			if !s.wants(event) {
+				continue
+			}
+			select {
+			case s.ch <- event:
+			default:
+				fmt.Println("Dropping slow rate event subscriber")
+				b.remove(s)
+			}
+		}
+	}
+}
+
+func makeRateEvent(change db.RateChange) RateEvent {
+	return RateEvent{Id: change.Id, CurrencyPair: change.CurrencyPair, Record: change.RateRecord}
+}
+
+func (b *Broker) PublishRequest(event RequestEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for s := range b.requestSubscribers {
+		select {
+		case s.ch <- event:
+		default:
+			fmt.Println("Dropping slow request event subscriber")
+			b.removeRequests(s)
+		}
+	}
+}
+
+// Subscribe registers a subscriber for the given pairs, or for every pair when
+// there are none. When lastEventId is set, the latest stored events newer than
+// it, read from the store, are returned to be sent before anything read from
+// the subscription. A subscriber without lastEventId only gets new events.
+func (b *Broker) Subscribe(pairs []db.CurrencyPair, lastEventId uint64) (*Subscription, []RateEvent, error) {
+	ch := make(chan RateEvent, b.bufferSize)
+	s := &Subscription{C: ch, ch: ch, pairs: make(map[db.CurrencyPair]bool)}
+	for _, pair := range pairs {
+		s.pairs[pair] = true
+	}
+
+	b.mu.Lock()
+	// Events up to the id delivered last are replayed, later ones come live.
+	// A lastEventId ahead of it comes from a server that has polled since.
+	s.After = max(b.lastId, lastEventId)
+	b.subscribers[s] = struct{}{}
+	b.mu.Unlock()
+
+	if lastEventId == 0 {
+		return s, nil, nil
+	}
+	changes, err := b.store.GetLatestRateChanges(pairs, lastEventId, s.After, b.replaySize)
+	if err != nil {
+		b.Unsubscribe(s)
+		return nil, nil, err
+	}
+	missed := make([]RateEvent, len(changes))
+	for i, change := range changes {
+		missed[i] = makeRateEvent(change)
+	}
+	return s, missed, nil
+}
+
+func (b *Broker) Unsubscribe(s *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(s)
+}
+
+func (b *Broker) remove(s *Subscription) {
+	if _, ok := b.subscribers[s]; ok {
+		delete(b.subscribers, s)
+		close(s.ch)
+	}
+}
+
+func (b *Broker) SubscribeRequests() *RequestSubscription {
+	ch := make(chan RequestEvent, b.bufferSize)
+	s := &RequestSubscription{C: ch, ch: ch}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.requestSubscribers[s] = struct{}{}
+	return s
+}
+
+func (b *Broker) UnsubscribeRequests(s *RequestSubscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.removeRequests(s)
+}
+
+func (b *Broker) removeRequests(s *RequestSubscription) {
+	if _, ok := b.requestSubscribers[s]; ok {
+		delete(b.requestSubscribers, s)
+		close(s.ch)
+	}
+}
@@ -0,0 +1,227 @@
+package events
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/artem98/ExchangeRateService/server/rates/db"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	eurUsd = db.CurrencyPair{Currency1: "EUR", Currency2: "USD"}
+	gbpUsd = db.CurrencyPair{Currency1: "GBP", Currency2: "USD"}
+)
+
+// historyStore keeps the stored rates in memory, numbered like rate_history.
+type historyStore struct {
+	mu      sync.Mutex
+	changes []db.RateChange
+	err     error
+}
+
+func (s *historyStore) store(pair db.CurrencyPair, rate string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.changes = append(s.changes, db.RateChange{
+		Id:           uint64(len(s.changes) + 1),
+		CurrencyPair: pair,
+		RateRecord:   db.RateRecord{Rate: decimal.RequireFromString(rate)},
+	})
+}
+
+func (s *historyStore) LastRateChangeId() (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return uint64(len(s.changes)), s.err
+}
+
+func (s *historyStore) GetRateChanges(afterId uint64, limit int) ([]db.RateChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changes := s.changes[min(afterId, uint64(len(s.changes))):]
+	return slices.Clone(changes[:min(limit, len(changes))]), s.err
+}
+
+func (s *historyStore) GetLatestRateChanges(pairs []db.CurrencyPair, afterId, upToId uint64, limit int) ([]db.RateChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var changes []db.RateChange
+	for _, change := range s.changes {
+		if change.Id > afterId && change.Id <= upToId && (len(pairs) == 0 || slices.Contains(pairs, change.CurrencyPair)) {
+			changes = append(changes, change)
+		}
+	}
+	return changes[max(len(changes)-limit, 0):], s.err
+}
+
+func makeBroker(t *testing.T, store *historyStore, replaySize, bufferSize int) *Broker {
+	t.Helper()
+	b, err := MakeBroker(store, replaySize, bufferSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func poll(t *testing.T, b *Broker) {
+	t.Helper()
+	if err := b.Poll(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBrokerDeliversSubscribedPairs(t *testing.T) {
+	store := &historyStore{}
+	b := makeBroker(t, store, 10, 10)
+	s, missed, err := b.Subscribe([]db.CurrencyPair{eurUsd}, 0)
+	if err != nil || len(missed) != 0 {
+		t.Fatalf("expected nothing to replay, got %v %v", missed, err)
+	}
+
+	store.store(gbpUsd, "1.27")
+	store.store(eurUsd, "1.08")
+	poll(t, b)
+
+	event := <-s.C
+	if event.CurrencyPair != eurUsd || event.Id != 2 || !event.Record.Rate.Equal(decimal.RequireFromString("1.08")) {
+		t.Errorf("unexpected event %+v", event)
+	}
+	select {
+	case event := <-s.C:
+		t.Errorf("unexpected extra event %+v", event)
+	default:
+	}
+}
+
+func TestBrokerDeliversOnlyRatesStoredAfterStart(t *testing.T) {
+	store := &historyStore{}
+	store.store(eurUsd, "1.07")
+	b := makeBroker(t, store, 10, 10)
+	s, _, _ := b.Subscribe(nil, 1)
+
+	store.store(eurUsd, "1.08")
+	poll(t, b)
+	poll(t, b)
+
+	if event := <-s.C; event.Id != 2 {
+		t.Errorf("expected event 2, got %+v", event)
+	}
+	select {
+	case event := <-s.C:
+		t.Errorf("unexpected extra event %+v", event)
+	default:
+	}
+}
+
+func TestBrokerReplaysAfterLastEventId(t *testing.T) {
+	store := &historyStore{}
+	b := makeBroker(t, store, 2, 10)
+	store.store(eurUsd, "1.07")
+	store.store(gbpUsd, "1.27")
+	store.store(eurUsd, "1.08")
+	store.store(eurUsd, "1.09")
+	poll(t, b)
+
+	// A new subscriber gets no history, without reading the store.
+	store.err = errors.New("db down")
+	_, missed, err := b.Subscribe(nil, 0)
+	if err != nil || len(missed) != 0 {
+		t.Errorf("expected nothing to replay, got %+v, %v", missed, err)
+	}
+	store.err = nil
+
+	// At most the two most recent events are replayed.
+	_, missed, _ = b.Subscribe(nil, 1)
+	if len(missed) != 2 || missed[0].Id != 3 || missed[1].Id != 4 {
+		t.Errorf("expected events 3 and 4, got %+v", missed)
+	}
+
+	_, missed, _ = b.Subscribe([]db.CurrencyPair{eurUsd}, 3)
+	if len(missed) != 1 || missed[0].Id != 4 {
+		t.Errorf("expected event 4, got %+v", missed)
+	}
+
+	// Stored by another server before this one polled, but after it started.
+	store.store(gbpUsd, "1.28")
+	_, missed, _ = b.Subscribe([]db.CurrencyPair{eurUsd, gbpUsd}, 1)
+	if len(missed) != 2 || missed[0].Id != 3 || missed[1].Id != 4 {
+		t.Errorf("expected events 3 and 4, got %+v", missed)
+	}
+}
+
+func TestBrokerSkipsEventsTheSubscriberHas(t *testing.T) {
+	store := &historyStore{}
+	b := makeBroker(t, store, 10, 10)
+	store.store(eurUsd, "1.07")
+	store.store(eurUsd, "1.08")
+
+	// Event 1 was received from another server, which polled earlier.
+	s, missed, _ := b.Subscribe(nil, 1)
+	if len(missed) != 0 || s.After != 1 {
+		t.Fatalf("expected nothing to replay after 1, got %+v after %d", missed, s.After)
+	}
+	poll(t, b)
+	if event := <-s.C; event.Id != 2 {
+		t.Errorf("expected event 2, got %+v", event)
+	}
+}
+
+func TestBrokerReportsStoreErrors(t *testing.T) {
+	store := &historyStore{err: errors.New("db down")}
+	if _, err := MakeBroker(store, 10, 10); err == nil {
+		t.Error("expected an error making the broker")
+	}
+
+	store.err = nil
+	b := makeBroker(t, store, 10, 10)
+	store.err = errors.New("db down")
+	if _, _, err := b.Subscribe(nil, 1); err == nil {
+		t.Error("expected an error resuming a subscription")
+	}
+	if err := b.Poll(); err == nil {
+		t.Error("expected an error polling")
+	}
+}
+
+func TestBrokerDropsSlowSubscriber(t *testing.T) {
+	store := &historyStore{}
+	b := makeBroker(t, store, 10, 1)
+	s, _, _ := b.Subscribe(nil, 0)
+
+	store.store(eurUsd, "1.07")
+	store.store(eurUsd, "1.08")
+	poll(t, b)
+
+	if event, ok := <-s.C; !ok || event.Id != 1 {
+		t.Errorf("expected buffered event 1, got %+v", event)
+	}
+	if _, ok := <-s.C; ok {
+		t.Errorf("expected subscription to be closed")
+	}
+
+	// Unsubscribing a dropped subscriber is harmless, and it resumes from the store.
+	b.Unsubscribe(s)
+	_, missed, _ := b.Subscribe(nil, 1)
+	if len(missed) != 1 || missed[0].Id != 2 {
+		t.Errorf("expected event 2 to be replayed, got %+v", missed)
+	}
+}
+
+func TestBrokerDeliversRequestEvents(t *testing.T) {
+	b := makeBroker(t, &historyStore{}, 10, 1)
+	s := b.SubscribeRequests()
+
+	b.PublishRequest(RequestEvent{RequestId: 42, CurrencyPair: eurUsd, Status: db.RequestOk})
+	if event := <-s.C; event.RequestId != 42 || event.Status != db.RequestOk {
+		t.Errorf("unexpected event %+v", event)
+	}
+
+	b.UnsubscribeRequests(s)
+	if _, ok := <-s.C; ok {
+		t.Errorf("expected subscription to be closed")
+	}
+	b.PublishRequest(RequestEvent{RequestId: 43, CurrencyPair: eurUsd, Status: db.RequestFailed})
+}
@@ -0,0 +1,118 @@
+package external
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/artem98/ExchangeRateService/server/rates/utils"
+	"github.com/shopspring/decimal"
+)
+
+const ConsensusProviderName = "consensus"
+
+// ConsensusProvider asks all of its providers concurrently and answers with the
+// median of their rates. Answers deviating from the median by more than
+// MaxDeviation (relative, 0.01 means 1%) are discarded as outliers, and fewer
+// than MinSources remaining answers is reported as an error.
+type ConsensusProvider struct {
+	providers    []RateProvider
+	MaxDeviation float64
+	MinSources   int
+}
+
+// MakeConsensusProvider requires minSources answers, or all of them when there
+// are fewer providers.
+func MakeConsensusProvider(maxDeviation float64, minSources int, providers ...RateProvider) *ConsensusProvider {
+	return &ConsensusProvider{
+		providers:    providers,
+		MaxDeviation: maxDeviation,
+		MinSources:   min(minSources, len(providers)),
+	}
+}
+
+func (c *ConsensusProvider) Name() string {
+	return ConsensusProviderName
+}
+
+type sourcedRate struct {
+	source string
+	rate   decimal.Decimal
+}
+
+func (c *ConsensusProvider) FetchRate(currency1, currency2 string) (Quote, error) {
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		rates []sourcedRate
+		errs  []error
+	)
+
+	for _, p := range c.providers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			quote, err := p.FetchRate(currency1, currency2)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+				return
+			}
+			rates = append(rates, sourcedRate{source: p.Name(), rate: quote.Rate})
+		}()
+	}
+	wg.Wait()
+
+	accepted := c.dropOutliers(rates)
+	if len(accepted) < c.MinSources {
+		// Every provider may have answered, with too few of them agreeing.
+		if dropped := len(rates) - len(accepted); dropped > 0 {
+			errs = append(errs, fmt.Errorf("%d quotes dropped as outliers", dropped))
+		}
+		return Quote{}, fmt.Errorf("only %d of %d rate providers agree, %d required: %w",
+			len(accepted), len(c.providers), c.MinSources, errors.Join(errs...))
+	}
+
+	sources := make([]string, len(accepted))
+	for i, r := range accepted {
+		sources[i] = r.source
+	}
+
+	return Quote{
+		Rate:     median(accepted),
+		Provider: ConsensusProviderName,
+		Sources:  sources,
+		Spread:   accepted[len(accepted)-1].rate.Sub(accepted[0].rate),
+	}, nil
+}
+
+// dropOutliers returns the rates within MaxDeviation of the median, sorted ascending.
+func (c *ConsensusProvider) dropOutliers(rates []sourcedRate) []sourcedRate {
+	if len(rates) == 0 {
+		return nil
+	}
+
+	sort.Slice(rates, func(i, j int) bool { return rates[i].rate.LessThan(rates[j].rate) })
+	m := median(rates)
+	maxDeviation := m.Abs().Mul(decimal.NewFromFloat(c.MaxDeviation))
+
+	var accepted []sourcedRate
+	for _, r := range rates {
+		if r.rate.Sub(m).Abs().LessThanOrEqual(maxDeviation) {
+			accepted = append(accepted, r)
+		}
+	}
+	return accepted
+}
+
+// median expects rates sorted ascending.
+func median(rates []sourcedRate) decimal.Decimal {
+	n := len(rates)
+	if n%2 == 1 {
+		return rates[n/2].rate
+	}
+	return utils.TrimZeros(rates[n/2-1].rate.Add(rates[n/2].rate).Div(decimal.NewFromInt(2)))
+}
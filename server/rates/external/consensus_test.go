@@ -0,0 +1,111 @@
+package external
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/artem98/ExchangeRateService/server/rates/utils"
+)
+
+func TestConsensusProvider_MedianAndSpread(t *testing.T) {
+	consensus := MakeConsensusProvider(0.01, 2,
+		&stubProvider{name: "a", rate: "1.080"},
+		&stubProvider{name: "b", rate: "1.082"},
+		&stubProvider{name: "c", rate: "1.081"},
+	)
+
+	quote, err := consensus.FetchRate("EUR", "USD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if quote.Rate.String() != "1.081" {
+		t.Errorf("expected median 1.081, got %s", quote.Rate)
+	}
+	if quote.Spread.String() != "0.002" {
+		t.Errorf("expected spread 0.002, got %s", quote.Spread)
+	}
+	if quote.Provider != ConsensusProviderName || len(quote.Sources) != 3 {
+		t.Errorf("expected 3 sources from consensus, got %s %v", quote.Provider, quote.Sources)
+	}
+}
+
+func TestConsensusProvider_EvenMedianIsNotPadded(t *testing.T) {
+	consensus := MakeConsensusProvider(0.01, 2,
+		&stubProvider{name: "a", rate: "1.0840"},
+		&stubProvider{name: "b", rate: "1.0845"},
+	)
+
+	quote, err := consensus.FetchRate("EUR", "USD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := utils.FormatDecimal(quote.Rate); got != "1.08425" {
+		t.Errorf("expected median 1.08425, got %s", got)
+	}
+}
+
+func TestConsensusProvider_DiscardsOutliers(t *testing.T) {
+	consensus := MakeConsensusProvider(0.01, 2,
+		&stubProvider{name: "a", rate: "1.08"},
+		&stubProvider{name: "b", rate: "1.08"},
+		&stubProvider{name: "broken", rate: "10.8"},
+	)
+
+	quote, err := consensus.FetchRate("EUR", "USD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if quote.Rate.String() != "1.08" || !quote.Spread.IsZero() {
+		t.Errorf("expected 1.08 with no spread, got %s %s", quote.Rate, quote.Spread)
+	}
+	for _, source := range quote.Sources {
+		if source == "broken" {
+			t.Errorf("expected outlier to be discarded, got sources %v", quote.Sources)
+		}
+	}
+}
+
+func TestConsensusProvider_NotEnoughSources(t *testing.T) {
+	consensus := MakeConsensusProvider(0.01, 2,
+		&stubProvider{name: "a", rate: "1.08"},
+		&stubProvider{name: "b", err: errors.New("down")},
+		&stubProvider{name: "c", err: errors.New("down")},
+	)
+
+	if _, err := consensus.FetchRate("EUR", "USD"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestConsensusProvider_TooFewAgree(t *testing.T) {
+	consensus := MakeConsensusProvider(0.01, 2,
+		&stubProvider{name: "a", rate: "1.08"},
+		&stubProvider{name: "b", rate: "1.20"},
+	)
+
+	_, err := consensus.FetchRate("EUR", "USD")
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "dropped as outliers") || strings.Contains(err.Error(), "%!") {
+		t.Errorf("expected the outliers to be reported, got %q", err)
+	}
+}
+
+func TestConsensusProvider_ConfiguredDeviationAndSources(t *testing.T) {
+	providers := []RateProvider{
+		&stubProvider{name: "a", rate: "1.08"},
+		&stubProvider{name: "b", rate: "1.10"},
+		&stubProvider{name: "c", rate: "1.09"},
+	}
+
+	// 1% around the median 1.09 keeps all three answers.
+	if quote, err := MakeConsensusProvider(0.01, 3, providers...).FetchRate("EUR", "USD"); err != nil || len(quote.Sources) != 3 {
+		t.Fatalf("expected 3 sources within 1%%, got %v %v", quote.Sources, err)
+	}
+	// 0.5% only keeps the median itself, fewer than required.
+	if _, err := MakeConsensusProvider(0.005, 2, providers...).FetchRate("EUR", "USD"); err == nil {
+		t.Fatal("expected too few sources within 0.5%")
+	}
+}
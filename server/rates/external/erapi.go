@@ -0,0 +1,63 @@
+package external
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+const ErApiProviderName = "erapi"
+
+func init() {
+	RegisterProvider(ErApiProviderName, func(ProviderOptions) RateProvider { return ErApiProvider{} })
+}
+
+// ErApiProvider fetches rates from the open ExchangeRate-API endpoint.
+type ErApiProvider struct{}
+
+func (ErApiProvider) Name() string {
+	return ErApiProviderName
+}
+
+type erApiResponse struct {
+	Result    string                     `json:"result"`
+	ErrorType string                     `json:"error-type"`
+	BaseCode  string                     `json:"base_code"`
+	Rates     map[string]decimal.Decimal `json:"rates"`
+}
+
+func (ErApiProvider) FetchRate(currency1, currency2 string) (Quote, error) {
+	url := fmt.Sprintf("https://open.er-api.com/v6/latest/%s", strings.ToUpper(currency1))
+
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return Quote{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Quote{}, &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	var parsed erApiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return Quote{}, err
+	}
+
+	if parsed.Result != "success" {
+		if parsed.ErrorType == "unsupported-code" {
+			return Quote{}, fmt.Errorf("rate for %s: %w", currency1, ErrRateNotFound)
+		}
+		return Quote{}, fmt.Errorf("API error: %s", parsed.ErrorType)
+	}
+
+	rate, ok := parsed.Rates[strings.ToUpper(currency2)]
+	if !ok {
+		return Quote{}, fmt.Errorf("rate for %s: %w", currency2, ErrRateNotFound)
+	}
+
+	return makeSingleSourceQuote(rate, ErApiProviderName), nil
+}
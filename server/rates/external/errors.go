@@ -0,0 +1,56 @@
+package external
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/artem98/ExchangeRateService/server/constants"
+)
+
+// ErrRateNotFound is returned when a provider does not quote the pair, usually
+// because one of the currencies is unknown to it.
+var ErrRateNotFound = errors.New("rate not found")
+
+// StatusError is returned when an upstream API answers with an unexpected HTTP status.
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return "API error: " + e.Status
+}
+
+// httpClient is shared by the HTTP based providers, so a hanging upstream
+// fails with a timeout instead of blocking a worker forever.
+var httpClient = &http.Client{Timeout: constants.ProviderTimeout}
+
+// IsRetryable reports whether fetching the rate again may succeed. Timeouts,
+// transport problems, 408, 429 and 5xx answers are transient, unknown pairs and
+// other 4xx answers are not. An error joining the failures of several providers
+// is retryable when any of them is.
+func IsRetryable(err error) bool {
+	// Compared directly, a joined error wrapping it may still hold transient failures.
+	if err == nil || err == ErrRateNotFound {
+		return false
+	}
+
+	switch e := err.(type) {
+	case interface{ Unwrap() []error }:
+		for _, inner := range e.Unwrap() {
+			if IsRetryable(inner) {
+				return true
+			}
+		}
+		return false
+	case *StatusError:
+		return e.StatusCode == http.StatusRequestTimeout ||
+			e.StatusCode == http.StatusTooManyRequests ||
+			e.StatusCode >= http.StatusInternalServerError
+	}
+
+	if inner := errors.Unwrap(err); inner != nil {
+		return IsRetryable(inner)
+	}
+	return true
+}
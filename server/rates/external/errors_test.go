@@ -0,0 +1,33 @@
+package external
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestIsRetryable(t *testing.T) {
+	notFound := fmt.Errorf("rate for XXX: %w", ErrRateNotFound)
+	timeout := &net.DNSError{Err: "timeout", IsTimeout: true}
+
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"unknown currency", notFound, false},
+		{"bad request", &StatusError{StatusCode: 400, Status: "400 Bad Request"}, false},
+		{"not found status", &StatusError{StatusCode: 404, Status: "404 Not Found"}, false},
+		{"too many requests", &StatusError{StatusCode: 429, Status: "429 Too Many Requests"}, true},
+		{"server error", fmt.Errorf("frankfurter: %w", &StatusError{StatusCode: 503, Status: "503 Service Unavailable"}), true},
+		{"timeout", timeout, true},
+		{"all unknown", fmt.Errorf("all rate providers failed: %w", errors.Join(notFound, notFound)), false},
+		{"one transient", fmt.Errorf("all rate providers failed: %w", errors.Join(notFound, timeout)), true},
+	}
+	for _, c := range cases {
+		if got := IsRetryable(c.err); got != c.want {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, got)
+		}
+	}
+}
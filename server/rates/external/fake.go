@@ -0,0 +1,42 @@
+package external
+
+import (
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const FakeProviderName = "fake"
+
+func init() {
+	RegisterProvider(FakeProviderName, func(ProviderOptions) RateProvider { return MakeFakeProvider(3 * time.Second) })
+}
+
+var fakeRates = [...]string{"0.04", "0.89", "1.35", "33", "18.1", "9", "0.81", "0.33", "12.34", "2.93", "2.02", "1.09", "3.65", "0.11", "5.4"}
+
+// FakeProvider cycles through a fixed list of rates, pausing before each answer
+// to imitate a slow upstream.
+type FakeProvider struct {
+	mu    sync.Mutex
+	it    int
+	delay time.Duration
+}
+
+func MakeFakeProvider(delay time.Duration) *FakeProvider {
+	return &FakeProvider{delay: delay}
+}
+
+func (p *FakeProvider) Name() string {
+	return FakeProviderName
+}
+
+func (p *FakeProvider) FetchRate(currency1, currency2 string) (Quote, error) {
+	time.Sleep(p.delay)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.it++
+	p.it = p.it % len(fakeRates)
+	return makeSingleSourceQuote(decimal.RequireFromString(fakeRates[p.it]), FakeProviderName), nil
+}
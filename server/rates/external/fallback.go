@@ -0,0 +1,104 @@
+package external
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/artem98/ExchangeRateService/server/constants"
+)
+
+const FallbackProviderName = "fallback"
+
+// FallbackProvider asks an ordered list of providers for a rate and returns the
+// first successful answer. A provider that fails FailureThreshold times in a row
+// is ejected for EjectionTime and only asked again once every healthy provider
+// has failed too.
+type FallbackProvider struct {
+	providers        []RateProvider
+	FailureThreshold int
+	EjectionTime     time.Duration
+
+	mu     sync.Mutex
+	health map[string]*providerHealth
+	now    func() time.Time
+}
+
+type providerHealth struct {
+	consecutiveFailures int
+	ejectedUntil        time.Time
+}
+
+func MakeFallbackProvider(providers ...RateProvider) *FallbackProvider {
+	health := make(map[string]*providerHealth, len(providers))
+	for _, p := range providers {
+		health[p.Name()] = &providerHealth{}
+	}
+
+	return &FallbackProvider{
+		providers:        providers,
+		FailureThreshold: constants.ProviderFailureThreshold,
+		EjectionTime:     constants.ProviderEjectionTime,
+		health:           health,
+		now:              time.Now,
+	}
+}
+
+func (f *FallbackProvider) Name() string {
+	return FallbackProviderName
+}
+
+func (f *FallbackProvider) FetchRate(currency1, currency2 string) (Quote, error) {
+	var healthy, ejected []RateProvider
+	for _, p := range f.providers {
+		if f.isEjected(p.Name()) {
+			ejected = append(ejected, p)
+		} else {
+			healthy = append(healthy, p)
+		}
+	}
+
+	var errs []error
+	for _, p := range append(healthy, ejected...) {
+		quote, err := p.FetchRate(currency1, currency2)
+		if err == nil {
+			f.reportSuccess(p.Name())
+			return quote, nil
+		}
+		// A pair the provider does not support says nothing about its health.
+		if !errors.Is(err, ErrRateNotFound) {
+			f.reportFailure(p.Name())
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+	}
+
+	return Quote{}, fmt.Errorf("all rate providers failed: %w", errors.Join(errs...))
+}
+
+func (f *FallbackProvider) isEjected(name string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.now().Before(f.health[name].ejectedUntil)
+}
+
+func (f *FallbackProvider) reportSuccess(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.health[name].consecutiveFailures = 0
+	f.health[name].ejectedUntil = time.Time{}
+}
+
+func (f *FallbackProvider) reportFailure(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	h := f.health[name]
+	h.consecutiveFailures++
+	if h.consecutiveFailures >= f.FailureThreshold {
+		h.ejectedUntil = f.now().Add(f.EjectionTime)
+		fmt.Println("Rate provider", name, "ejected until", h.ejectedUntil.Format(time.RFC3339))
+	}
+}
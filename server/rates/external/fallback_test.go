@@ -0,0 +1,115 @@
+package external
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type stubProvider struct {
+	name  string
+	rate  string
+	err   error
+	calls int
+}
+
+func (p *stubProvider) Name() string {
+	return p.name
+}
+
+func (p *stubProvider) FetchRate(currency1, currency2 string) (Quote, error) {
+	p.calls++
+	if p.err != nil {
+		return Quote{}, p.err
+	}
+	return Quote{Rate: decimal.RequireFromString(p.rate), Provider: p.name}, nil
+}
+
+func TestFallbackProvider_FirstHealthyWins(t *testing.T) {
+	primary := &stubProvider{name: "primary", rate: "1.1"}
+	secondary := &stubProvider{name: "secondary", rate: "2.2"}
+	fallback := MakeFallbackProvider(primary, secondary)
+
+	quote, err := fallback.FetchRate("EUR", "USD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if quote.Provider != "primary" || quote.Rate.String() != "1.1" {
+		t.Errorf("expected primary 1.1, got %s %s", quote.Provider, quote.Rate)
+	}
+	if secondary.calls != 0 {
+		t.Errorf("expected secondary not to be called, got %d calls", secondary.calls)
+	}
+}
+
+func TestFallbackProvider_FallsBackOnError(t *testing.T) {
+	primary := &stubProvider{name: "primary", err: errors.New("down")}
+	secondary := &stubProvider{name: "secondary", rate: "2.2"}
+	fallback := MakeFallbackProvider(primary, secondary)
+
+	quote, err := fallback.FetchRate("EUR", "USD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if quote.Provider != "secondary" {
+		t.Errorf("expected secondary, got %s", quote.Provider)
+	}
+}
+
+func TestFallbackProvider_EjectsUnhealthy(t *testing.T) {
+	primary := &stubProvider{name: "primary", rate: "1.1", err: errors.New("down")}
+	secondary := &stubProvider{name: "secondary", rate: "2.2"}
+	fallback := MakeFallbackProvider(primary, secondary)
+	fallback.FailureThreshold = 2
+	fallback.EjectionTime = time.Minute
+
+	now := time.Now()
+	fallback.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if _, err := fallback.FetchRate("EUR", "USD"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if primary.calls != 2 {
+		t.Errorf("expected primary to be ejected after 2 calls, got %d calls", primary.calls)
+	}
+
+	now = now.Add(2 * time.Minute)
+	primary.err = nil
+	quote, err := fallback.FetchRate("EUR", "USD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if quote.Provider != "primary" {
+		t.Errorf("expected primary to be back after ejection, got %s", quote.Provider)
+	}
+}
+
+func TestFallbackProvider_UnsupportedPairsDoNotEject(t *testing.T) {
+	primary := &stubProvider{name: "primary", err: fmt.Errorf("rate for XXX: %w", ErrRateNotFound)}
+	secondary := &stubProvider{name: "secondary", rate: "2.2"}
+	fallback := MakeFallbackProvider(primary, secondary)
+	fallback.FailureThreshold = 2
+
+	for i := 0; i < 3; i++ {
+		fallback.FetchRate("EUR", "XXX")
+	}
+	if fallback.isEjected("primary") {
+		t.Errorf("expected lookups of an unsupported pair not to eject the primary")
+	}
+}
+
+func TestFallbackProvider_AllFailed(t *testing.T) {
+	fallback := MakeFallbackProvider(
+		&stubProvider{name: "primary", err: errors.New("down")},
+		&stubProvider{name: "secondary", err: errors.New("down too")},
+	)
+
+	if _, err := fallback.FetchRate("EUR", "USD"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -0,0 +1,56 @@
+package external
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+const FrankfurterProviderName = "frankfurter"
+
+func init() {
+	RegisterProvider(FrankfurterProviderName, func(ProviderOptions) RateProvider { return FrankfurterProvider{} })
+}
+
+// FrankfurterProvider fetches rates from the public Frankfurter API.
+type FrankfurterProvider struct{}
+
+func (FrankfurterProvider) Name() string {
+	return FrankfurterProviderName
+}
+
+type externalRateResponse struct {
+	Rates map[string]decimal.Decimal `json:"rates"`
+	Base  string                     `json:"base"`
+	Date  string                     `json:"date"`
+}
+
+func (FrankfurterProvider) FetchRate(currency1, currency2 string) (Quote, error) {
+	url := fmt.Sprintf("https://api.frankfurter.app/latest?from=%s&to=%s",
+		strings.ToUpper(currency1), strings.ToUpper(currency2))
+
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return Quote{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Quote{}, &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	var parsed externalRateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return Quote{}, err
+	}
+
+	rate, ok := parsed.Rates[currency2]
+	if !ok {
+		return Quote{}, fmt.Errorf("rate for %s: %w", currency2, ErrRateNotFound)
+	}
+
+	return makeSingleSourceQuote(rate, FrankfurterProviderName), nil
+}
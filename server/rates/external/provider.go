@@ -0,0 +1,138 @@
+package external
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/shopspring/decimal"
+)
+
+// Quote is an exchange rate together with the name of the provider that served it.
+// Sources lists the upstreams the rate is based on and Spread is the difference
+// between the highest and the lowest of their answers.
+type Quote struct {
+	Rate     decimal.Decimal
+	Provider string
+	Sources  []string
+	Spread   decimal.Decimal
+}
+
+func makeSingleSourceQuote(rate decimal.Decimal, provider string) Quote {
+	return Quote{Rate: rate, Provider: provider, Sources: []string{provider}}
+}
+
+// RateProvider fetches the current exchange rate of a currency pair from an upstream source.
+type RateProvider interface {
+	Name() string
+	FetchRate(currency1, currency2 string) (Quote, error)
+}
+
+type providerFactory = func(options ProviderOptions) RateProvider
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]providerFactory)
+)
+
+// RegisterProvider makes a provider implementation selectable by name via MakeProvider.
+func RegisterProvider(name string, factory providerFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, exists := registry[name]; exists {
+		panic(fmt.Sprintf("rate provider %q registered twice", name))
+	}
+	registry[name] = factory
+}
+
+// MakeProvider builds the registered provider with the given name.
+func MakeProvider(name string, options ProviderOptions) (RateProvider, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown rate provider %q, available: %v", name, ProviderNames())
+	}
+	return withLog(factory(options)), nil
+}
+
+const (
+	AggregationFallback  = "fallback"
+	AggregationConsensus = "consensus"
+)
+
+// ProviderOptions tunes the providers built by MakeProvider and MakeProviderChain.
+type ProviderOptions struct {
+	// RatesFile is the file read by the StaticFileProvider.
+	RatesFile string
+	// ConsensusDeviation and ConsensusMinSources configure the ConsensusProvider.
+	ConsensusDeviation  float64
+	ConsensusMinSources int
+}
+
+// MakeProviderChain builds the named providers in order. A single name yields that
+// provider as is, several names are combined into a FallbackProvider or a
+// ConsensusProvider depending on the aggregation mode.
+func MakeProviderChain(names []string, aggregation string, options ProviderOptions) (RateProvider, error) {
+	var providers []RateProvider
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		provider, err := MakeProvider(name, options)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("no rate providers configured")
+	}
+
+	switch aggregation {
+	case AggregationFallback:
+		if len(providers) == 1 {
+			return providers[0], nil
+		}
+		return MakeFallbackProvider(providers...), nil
+	case AggregationConsensus:
+		return MakeConsensusProvider(options.ConsensusDeviation, options.ConsensusMinSources, providers...), nil
+	default:
+		return nil, fmt.Errorf("unknown rate aggregation mode %q", aggregation)
+	}
+}
+
+// ProviderNames lists the names of all registered providers.
+func ProviderNames() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type loggedProvider struct {
+	RateProvider
+}
+
+func withLog(p RateProvider) RateProvider {
+	return loggedProvider{RateProvider: p}
+}
+
+func (p loggedProvider) FetchRate(currency1, currency2 string) (Quote, error) {
+	fmt.Println("Fetching rate for", currency1, "/", currency2, "from", p.Name())
+	quote, err := p.RateProvider.FetchRate(currency1, currency2)
+	if err != nil {
+		fmt.Println("Failed to fetch rate:", err.Error())
+	}
+	return quote, err
+}
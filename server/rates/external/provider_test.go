@@ -0,0 +1,101 @@
+package external
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMakeProvider_Registered(t *testing.T) {
+	for _, name := range []string{FrankfurterProviderName, FakeProviderName} {
+		provider, err := MakeProvider(name, ProviderOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %v", name, err)
+		}
+		if provider.Name() != name {
+			t.Errorf("expected provider %s, got %s", name, provider.Name())
+		}
+	}
+}
+
+func TestMakeProviderChain(t *testing.T) {
+	single, err := MakeProviderChain([]string{" fake "}, AggregationFallback, ProviderOptions{ConsensusDeviation: 0.01, ConsensusMinSources: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if single.Name() != FakeProviderName {
+		t.Errorf("expected provider %s, got %s", FakeProviderName, single.Name())
+	}
+
+	chain, err := MakeProviderChain([]string{"frankfurter", "fake"}, AggregationFallback, ProviderOptions{ConsensusDeviation: 0.01, ConsensusMinSources: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if chain.Name() != FallbackProviderName {
+		t.Errorf("expected provider %s, got %s", FallbackProviderName, chain.Name())
+	}
+
+	consensus, err := MakeProviderChain([]string{"frankfurter", "fake"}, AggregationConsensus, ProviderOptions{ConsensusDeviation: 0.01, ConsensusMinSources: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if consensus.Name() != ConsensusProviderName {
+		t.Errorf("expected provider %s, got %s", ConsensusProviderName, consensus.Name())
+	}
+
+	if _, err := MakeProviderChain(nil, AggregationFallback, ProviderOptions{ConsensusDeviation: 0.01, ConsensusMinSources: 2}); err == nil {
+		t.Error("expected error for empty chain, got nil")
+	}
+	if _, err := MakeProviderChain([]string{"fake"}, "vote", ProviderOptions{ConsensusDeviation: 0.01, ConsensusMinSources: 2}); err == nil {
+		t.Error("expected error for unknown aggregation, got nil")
+	}
+}
+
+func TestMakeProvider_Unknown(t *testing.T) {
+	_, err := MakeProvider("nope", ProviderOptions{})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "unknown rate provider") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
+
+func TestFakeProvider_CyclesRates(t *testing.T) {
+	provider := MakeFakeProvider(0)
+
+	first, err := provider.FetchRate("EUR", "USD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := provider.FetchRate("EUR", "USD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first.Rate.Equal(second.Rate) {
+		t.Errorf("expected different rates, got %s twice", first.Rate)
+	}
+	if first.Provider != FakeProviderName {
+		t.Errorf("expected provider %s, got %s", FakeProviderName, first.Provider)
+	}
+}
+
+func TestMakeProvider_StaticFileReadsGivenPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	if err := os.WriteFile(path, []byte(`{"EUR/USD": "1.0842"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	provider, err := MakeProvider(StaticFileProviderName, ProviderOptions{RatesFile: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	quote, err := provider.FetchRate("EUR", "USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quote.Rate.String() != "1.0842" {
+		t.Fatalf("expected 1.0842, got %s", quote.Rate)
+	}
+}
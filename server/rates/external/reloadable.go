@@ -0,0 +1,36 @@
+package external
+
+import "sync"
+
+// ReloadableProvider forwards to a provider that can be replaced while the
+// server runs. Components keep it for their whole life, so jobs already queued
+// fetch from the new provider once it is set.
+type ReloadableProvider struct {
+	mu       sync.RWMutex
+	provider RateProvider
+}
+
+func MakeReloadableProvider(provider RateProvider) *ReloadableProvider {
+	return &ReloadableProvider{provider: provider}
+}
+
+// Set replaces the provider. Fetches in progress finish with the old one.
+func (r *ReloadableProvider) Set(provider RateProvider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.provider = provider
+}
+
+func (r *ReloadableProvider) current() RateProvider {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.provider
+}
+
+func (r *ReloadableProvider) Name() string {
+	return r.current().Name()
+}
+
+func (r *ReloadableProvider) FetchRate(currency1, currency2 string) (Quote, error) {
+	return r.current().FetchRate(currency1, currency2)
+}
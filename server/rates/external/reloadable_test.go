@@ -0,0 +1,19 @@
+package external
+
+import "testing"
+
+func TestReloadableProvider_Set(t *testing.T) {
+	first := MakeFakeProvider(0)
+	provider := MakeReloadableProvider(first)
+	if provider.Name() != FakeProviderName {
+		t.Fatalf("expected provider %s, got %s", FakeProviderName, provider.Name())
+	}
+
+	provider.Set(MakeStaticFileProvider("missing.json"))
+	if provider.Name() != StaticFileProviderName {
+		t.Fatalf("expected provider %s after Set, got %s", StaticFileProviderName, provider.Name())
+	}
+	if _, err := provider.FetchRate("EUR", "USD"); err == nil {
+		t.Fatal("expected the fetch to go to the new provider")
+	}
+}
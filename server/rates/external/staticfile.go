@@ -0,0 +1,53 @@
+package external
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+const StaticFileProviderName = "file"
+
+func init() {
+	RegisterProvider(StaticFileProviderName, func(options ProviderOptions) RateProvider {
+		return MakeStaticFileProvider(options.RatesFile)
+	})
+}
+
+// StaticFileProvider serves rates from a JSON file mapping pair codes to rates,
+// e.g. {"EUR/USD": 1.0842} or {"EUR/USD": "1.0842"}. The file is re-read on every fetch so it can be
+// edited while the server runs.
+type StaticFileProvider struct {
+	path string
+}
+
+func MakeStaticFileProvider(path string) StaticFileProvider {
+	return StaticFileProvider{path: path}
+}
+
+func (StaticFileProvider) Name() string {
+	return StaticFileProviderName
+}
+
+func (p StaticFileProvider) FetchRate(currency1, currency2 string) (Quote, error) {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return Quote{}, fmt.Errorf("failed to read rates file: %w", err)
+	}
+
+	var rates map[string]decimal.Decimal
+	if err := json.Unmarshal(data, &rates); err != nil {
+		return Quote{}, fmt.Errorf("failed to parse rates file: %w", err)
+	}
+
+	pair := strings.ToUpper(currency1 + "/" + currency2)
+	rate, ok := rates[pair]
+	if !ok {
+		return Quote{}, fmt.Errorf("rate for %s: %w", pair, ErrRateNotFound)
+	}
+
+	return makeSingleSourceQuote(rate, StaticFileProviderName), nil
+}
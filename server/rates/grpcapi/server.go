@@ -0,0 +1,200 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/artem98/ExchangeRateService/server/rates/db"
+	"github.com/artem98/ExchangeRateService/server/rates/events"
+	"github.com/artem98/ExchangeRateService/server/rates/grpcapi/ratespb"
+	"github.com/artem98/ExchangeRateService/server/rates/handlers"
+	"github.com/artem98/ExchangeRateService/server/rates/triangulation"
+	"github.com/artem98/ExchangeRateService/server/rates/utils"
+	"github.com/artem98/ExchangeRateService/server/rates/worker"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Server implements the gRPC RateService on top of the dependencies of the
+// HTTP handler, so both APIs share the cache, the worker and the events.
+type Server struct {
+	ratespb.UnimplementedRateServiceServer
+	Handler *handlers.Handler
+}
+
+func MakeServer(h *handlers.Handler, opts ...grpc.ServerOption) *grpc.Server {
+	server := grpc.NewServer(opts...)
+	ratespb.RegisterRateServiceServer(server, &Server{Handler: h})
+	return server
+}
+
+// CancelStreamsWith ends the streams of the server when ctx is done. Streams
+// such as WatchRates only end when the client leaves, which would otherwise
+// hold up GracefulStop.
+func CancelStreamsWith(ctx context.Context) grpc.ServerOption {
+	return grpc.StreamInterceptor(func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		streamCtx, cancel := context.WithCancel(stream.Context())
+		defer cancel()
+		stop := context.AfterFunc(ctx, cancel)
+		defer stop()
+		return handler(srv, &cancelableStream{ServerStream: stream, ctx: streamCtx})
+	})
+}
+
+type cancelableStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *cancelableStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *Server) GetRate(ctx context.Context, req *ratespb.GetRateRequest) (*ratespb.Rate, error) {
+	currency1, currency2, err := utils.ParseCurrencyPair(req.GetCurrencyPair())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if req.GetAsOf() != nil {
+		record, err := s.Handler.Db.GetRateAsOf(currency1, currency2, req.GetAsOf().AsTime())
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		return makeRate(currency1, currency2, record, nil), nil
+	}
+
+	record, legs, err := s.Handler.ResolveRate(currency1, currency2)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return makeRate(currency1, currency2, record, legs), nil
+}
+
+func (s *Server) RequestUpdate(ctx context.Context, req *ratespb.RequestUpdateRequest) (*ratespb.RequestUpdateResponse, error) {
+	currency1, currency2, err := utils.ParseCurrencyPair(req.GetCurrencyPair())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if req.GetCallbackUrl() != "" {
+		if err := s.Handler.ValidateCallbackUrl(ctx, req.GetCallbackUrl()); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	requestId, deduplicated, err := s.Handler.RequestUpdate(currency1, currency2, req.GetCallbackUrl())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &ratespb.RequestUpdateResponse{UpdateRequestId: requestId, Deduplicated: deduplicated}, nil
+}
+
+func (s *Server) GetUpdateRequest(ctx context.Context, req *ratespb.GetUpdateRequestRequest) (*ratespb.UpdateRequest, error) {
+	record, err := s.Handler.Db.GetUpdateRequest(req.GetUpdateRequestId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	response := &ratespb.UpdateRequest{
+		UpdateRequestId: record.Id,
+		CurrencyPair:    record.Currency1 + "/" + record.Currency2,
+		Status:          updateStatuses[record.Status],
+		CreatedAt:       timestamppb.New(record.CreatedAt),
+		FailureReason:   record.FailureReason,
+		Attempts:        int32(record.Attempts),
+		LastError:       record.LastError,
+	}
+	if !record.CompletedAt.IsZero() {
+		response.CompletedAt = timestamppb.New(record.CompletedAt)
+	}
+	if record.Status == db.RequestOk {
+		response.Rate = makeRate(record.Currency1, record.Currency2, record.Rate, nil)
+	}
+	return response, nil
+}
+
+// WatchRates streams the rates stored by any server sharing the database. The
+// event ids are the ids of the stored rates, so a client that fell behind or
+// lost its server resumes on any server with last_event_id.
+func (s *Server) WatchRates(req *ratespb.WatchRatesRequest, stream grpc.ServerStreamingServer[ratespb.RateEvent]) error {
+	var pairs []db.CurrencyPair
+	for _, code := range req.GetCurrencyPairs() {
+		currency1, currency2, err := utils.ParseCurrencyPair(code)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		pairs = append(pairs, db.CurrencyPair{Currency1: currency1, Currency2: currency2})
+	}
+
+	subscription, missed, err := s.Handler.Events.Subscribe(pairs, req.GetLastEventId())
+	if err != nil {
+		return toStatus(err)
+	}
+	defer s.Handler.Events.Unsubscribe(subscription)
+
+	for _, event := range missed {
+		if err := stream.Send(makeRateEvent(event)); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-subscription.C:
+			if !ok {
+				return status.Error(codes.Unavailable, "client fell behind, watch again with last_event_id")
+			}
+			if err := stream.Send(makeRateEvent(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+var updateStatuses = map[db.RequestStatus]ratespb.UpdateStatus{
+	db.RequestSubmitted: ratespb.UpdateStatus_UPDATE_STATUS_SUBMITTED,
+	db.RequestOk:        ratespb.UpdateStatus_UPDATE_STATUS_OK,
+	db.RequestFailed:    ratespb.UpdateStatus_UPDATE_STATUS_FAILED,
+	db.RequestRejected:  ratespb.UpdateStatus_UPDATE_STATUS_REJECTED,
+}
+
+func toStatus(err error) error {
+	if errors.Is(err, db.ErrNoSuchPair) || errors.Is(err, db.ErrNoRateAtTime) || errors.Is(err, db.ErrNoSuchRequest) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	if errors.Is(err, worker.ErrQueueFull) {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+func makeRate(currency1, currency2 string, record db.RateRecord, legs []triangulation.Leg) *ratespb.Rate {
+	rate := &ratespb.Rate{
+		CurrencyPair: currency1 + "/" + currency2,
+		Rate:         utils.FormatDecimal(record.Rate),
+		UpdateTime:   timestamppb.New(record.UpdateTime),
+		Provider:     record.Provider,
+		Sources:      record.Sources,
+		Spread:       utils.FormatDecimal(record.Spread),
+	}
+	for _, leg := range legs {
+		rate.DerivedVia = append(rate.DerivedVia, &ratespb.Leg{
+			CurrencyPair: leg.Currency1 + "/" + leg.Currency2,
+			Inverse:      leg.Inverse,
+			Rate:         utils.FormatDecimal(leg.Record.Rate),
+			UpdateTime:   timestamppb.New(leg.Record.UpdateTime),
+		})
+	}
+	return rate
+}
+
+func makeRateEvent(event events.RateEvent) *ratespb.RateEvent {
+	return &ratespb.RateEvent{
+		EventId: event.Id,
+		Rate:    makeRate(event.Currency1, event.Currency2, event.Record, nil),
+	}
+}
@@ -0,0 +1,286 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/artem98/ExchangeRateService/server/rates/db"
+	"github.com/artem98/ExchangeRateService/server/rates/events"
+	"github.com/artem98/ExchangeRateService/server/rates/grpcapi/ratespb"
+	"github.com/artem98/ExchangeRateService/server/rates/handlers"
+	"github.com/artem98/ExchangeRateService/server/rates/worker"
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// stubDb implements the methods the tests use, any other call panics.
+type stubDb struct {
+	db.DataBase
+	rates    map[db.CurrencyPair]db.RateRecord
+	requests map[uint64]db.UpdateRequestRecord
+	placed   []db.CurrencyPair
+}
+
+func (s *stubDb) GetRateByPair(currency1, currency2 string) (db.RateRecord, error) {
+	record, ok := s.rates[db.CurrencyPair{Currency1: currency1, Currency2: currency2}]
+	if !ok {
+		return db.RateRecord{}, db.ErrNoSuchPair
+	}
+	return record, nil
+}
+
+func (s *stubDb) GetRateAsOf(currency1, currency2 string, asOf time.Time) (db.RateRecord, error) {
+	return db.RateRecord{}, db.ErrNoRateAtTime
+}
+
+func (s *stubDb) GetUpdateRequest(id uint64) (db.UpdateRequestRecord, error) {
+	record, ok := s.requests[id]
+	if !ok {
+		return db.UpdateRequestRecord{}, db.ErrNoSuchRequest
+	}
+	return record, nil
+}
+
+func (s *stubDb) PlaceRequest(currency1, currency2, callbackUrl, leaseOwner string) (uint64, error) {
+	s.placed = append(s.placed, db.CurrencyPair{Currency1: currency1, Currency2: currency2})
+	return uint64(len(s.placed)), nil
+}
+
+type stubWorker struct {
+	planned int
+}
+
+func (w *stubWorker) PlanJob(job worker.Job) error {
+	w.planned++
+	return nil
+}
+
+func startServer(t *testing.T, h *handlers.Handler) ratespb.RateServiceClient {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server := MakeServer(h)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return ratespb.NewRateServiceClient(conn)
+}
+
+// historyStore keeps the stored rates in memory, numbered like rate_history.
+type historyStore struct {
+	mu      sync.Mutex
+	changes []db.RateChange
+}
+
+func (s *historyStore) LastRateChangeId() (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return uint64(len(s.changes)), nil
+}
+
+func (s *historyStore) GetRateChanges(afterId uint64, limit int) ([]db.RateChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changes := s.changes[min(afterId, uint64(len(s.changes))):]
+	return slices.Clone(changes[:min(limit, len(changes))]), nil
+}
+
+func (s *historyStore) GetLatestRateChanges(pairs []db.CurrencyPair, afterId, upToId uint64, limit int) ([]db.RateChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var changes []db.RateChange
+	for _, change := range s.changes {
+		if change.Id > afterId && change.Id <= upToId && (len(pairs) == 0 || slices.Contains(pairs, change.CurrencyPair)) {
+			changes = append(changes, change)
+		}
+	}
+	return changes[max(len(changes)-limit, 0):], nil
+}
+
+// makeTestHandler also returns a function storing a rate and delivering it to
+// the rate watchers.
+func makeTestHandler(t *testing.T, database *stubDb) (*handlers.Handler, *stubWorker, func(currency1, currency2, rate string)) {
+	t.Helper()
+	history := &historyStore{}
+	broker, err := events.MakeBroker(history, 10, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publish := func(currency1, currency2, rate string) {
+		history.mu.Lock()
+		history.changes = append(history.changes, db.RateChange{
+			Id:           uint64(len(history.changes) + 1),
+			CurrencyPair: db.CurrencyPair{Currency1: currency1, Currency2: currency2},
+			RateRecord:   db.RateRecord{Rate: decimal.RequireFromString(rate)},
+		})
+		history.mu.Unlock()
+		if err := broker.Poll(); err != nil {
+			t.Error(err)
+		}
+	}
+
+	w := &stubWorker{}
+	return &handlers.Handler{
+		Db:     database,
+		Worker: w,
+		Cache:  worker.MakeRateJobsCache(time.Minute),
+		Pivots: []string{"USD"},
+		Events: broker,
+	}, w, publish
+}
+
+func TestGetRate(t *testing.T) {
+	updateTime := time.Date(2025, 3, 14, 10, 0, 0, 0, time.UTC)
+	database := &stubDb{rates: map[db.CurrencyPair]db.RateRecord{
+		{Currency1: "EUR", Currency2: "USD"}: {Rate: decimal.RequireFromString("1.0850"), UpdateTime: updateTime, Provider: "frankfurter"},
+	}}
+	h, _, _ := makeTestHandler(t, database)
+	client := startServer(t, h)
+	ctx := context.Background()
+
+	rate, err := client.GetRate(ctx, &ratespb.GetRateRequest{CurrencyPair: "eur/usd"})
+	if err != nil {
+		t.Fatalf("GetRate failed: %v", err)
+	}
+	if rate.GetCurrencyPair() != "EUR/USD" || rate.GetRate() != "1.0850" || !rate.GetUpdateTime().AsTime().Equal(updateTime) {
+		t.Errorf("unexpected rate %v", rate)
+	}
+
+	// The inverse pair is derived from the stored one.
+	rate, err = client.GetRate(ctx, &ratespb.GetRateRequest{CurrencyPair: "USD/EUR"})
+	if err != nil || len(rate.GetDerivedVia()) != 1 || !rate.GetDerivedVia()[0].GetInverse() {
+		t.Errorf("expected derived rate, got %v, %v", rate, err)
+	}
+
+	_, err = client.GetRate(ctx, &ratespb.GetRateRequest{CurrencyPair: "EURUSD"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument, got %v", err)
+	}
+	_, err = client.GetRate(ctx, &ratespb.GetRateRequest{CurrencyPair: "GBP/MXN"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound, got %v", err)
+	}
+	_, err = client.GetRate(ctx, &ratespb.GetRateRequest{CurrencyPair: "EUR/USD", AsOf: timestamppb.New(updateTime)})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound for as_of, got %v", err)
+	}
+}
+
+func TestRequestUpdateAndStatus(t *testing.T) {
+	created := time.Date(2025, 3, 14, 10, 0, 0, 0, time.UTC)
+	database := &stubDb{requests: map[uint64]db.UpdateRequestRecord{
+		5: {
+			Id: 5, Currency1: "EUR", Currency2: "USD", Status: db.RequestFailed,
+			CreatedAt: created, CompletedAt: created.Add(time.Second), FailureReason: "timeout",
+		},
+	}}
+	h, w, _ := makeTestHandler(t, database)
+	client := startServer(t, h)
+	ctx := context.Background()
+
+	first, err := client.RequestUpdate(ctx, &ratespb.RequestUpdateRequest{CurrencyPair: "EUR/USD"})
+	if err != nil || first.GetUpdateRequestId() != 1 || first.GetDeduplicated() {
+		t.Fatalf("unexpected response %v, %v", first, err)
+	}
+	second, err := client.RequestUpdate(ctx, &ratespb.RequestUpdateRequest{CurrencyPair: "EUR/USD"})
+	if err != nil || second.GetUpdateRequestId() != 1 || !second.GetDeduplicated() {
+		t.Errorf("expected the cached request, got %v, %v", second, err)
+	}
+	if w.planned != 1 {
+		t.Errorf("expected one planned job, got %d", w.planned)
+	}
+
+	_, err = client.RequestUpdate(ctx, &ratespb.RequestUpdateRequest{CurrencyPair: "GBP/USD", CallbackUrl: "https://example.com"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument while callbacks are disabled, got %v", err)
+	}
+
+	request, err := client.GetUpdateRequest(ctx, &ratespb.GetUpdateRequestRequest{UpdateRequestId: 5})
+	if err != nil {
+		t.Fatalf("GetUpdateRequest failed: %v", err)
+	}
+	if request.GetStatus() != ratespb.UpdateStatus_UPDATE_STATUS_FAILED || request.GetFailureReason() != "timeout" ||
+		request.GetCompletedAt() == nil || request.GetRate() != nil {
+		t.Errorf("unexpected update request %v", request)
+	}
+
+	_, err = client.GetUpdateRequest(ctx, &ratespb.GetUpdateRequestRequest{UpdateRequestId: 6})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound, got %v", err)
+	}
+}
+
+func TestWatchRates(t *testing.T) {
+	h, _, publish := makeTestHandler(t, &stubDb{})
+	publish("EUR", "USD", "1.07")
+	publish("EUR", "USD", "1.08")
+	client := startServer(t, h)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := client.WatchRates(ctx, &ratespb.WatchRatesRequest{CurrencyPairs: []string{"EUR/USD"}, LastEventId: 1})
+	if err != nil {
+		t.Fatalf("WatchRates failed: %v", err)
+	}
+
+	event, err := stream.Recv()
+	if err != nil || event.GetEventId() != 2 || event.GetRate().GetRate() != "1.08" {
+		t.Fatalf("expected replayed event 2, got %v, %v", event, err)
+	}
+
+	// The subscription is in place once the replayed event has arrived.
+	publish("GBP", "USD", "1.27")
+	publish("EUR", "USD", "1.09")
+	event, err = stream.Recv()
+	if err != nil || event.GetEventId() != 4 || event.GetRate().GetCurrencyPair() != "EUR/USD" {
+		t.Errorf("expected live event 4, got %v, %v", event, err)
+	}
+}
+
+func TestWatchRatesResumesWithLastEventId(t *testing.T) {
+	h, _, publish := makeTestHandler(t, &stubDb{})
+	publish("EUR", "USD", "1.07")
+	publish("EUR", "USD", "1.08")
+	client := startServer(t, h)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	watchCtx, leave := context.WithCancel(ctx)
+	stream, err := client.WatchRates(watchCtx, &ratespb.WatchRatesRequest{LastEventId: 1})
+	if err != nil {
+		t.Fatalf("WatchRates failed: %v", err)
+	}
+	event, err := stream.Recv()
+	if err != nil || event.GetEventId() != 2 {
+		t.Fatalf("expected missed event 2, got %v, %v", event, err)
+	}
+	leave()
+
+	publish("EUR", "USD", "1.09")
+	publish("GBP", "USD", "1.27")
+	stream, err = client.WatchRates(ctx, &ratespb.WatchRatesRequest{LastEventId: event.GetEventId()})
+	if err != nil {
+		t.Fatalf("WatchRates failed: %v", err)
+	}
+	for _, want := range []uint64{3, 4} {
+		event, err = stream.Recv()
+		if err != nil || event.GetEventId() != want {
+			t.Fatalf("expected missed event %d, got %v, %v", want, event, err)
+		}
+	}
+}
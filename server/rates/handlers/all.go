@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/artem98/ExchangeRateService/server/rates/db"
+	"github.com/artem98/ExchangeRateService/server/rates/utils"
+)
+
+type PairRateResponse struct {
+	CurrencyPair string `json:"currency_pair"`
+	RateResponse
+}
+
+type AllRatesResponse struct {
+	Rates []PairRateResponse `json:"rates"`
+}
+
+func (h *Handler) handleGetAllRates(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var base string
+	if baseParam := query.Get("base"); baseParam != "" {
+		var err error
+		base, err = utils.ParseCurrencyCode(baseParam)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Pairs can be listed comma-separated, in repeated parameters or both.
+	var pairs []db.CurrencyPair
+	for _, param := range query["currency_pairs"] {
+		for _, code := range strings.Split(param, ",") {
+			currency1, currency2, err := utils.ParseCurrencyPair(strings.TrimSpace(code))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			pairs = append(pairs, db.CurrencyPair{Currency1: currency1, Currency2: currency2})
+		}
+	}
+
+	records, err := h.Db.GetRates(base, pairs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := AllRatesResponse{Rates: []PairRateResponse{}}
+	for _, record := range records {
+		response.Rates = append(response.Rates, PairRateResponse{
+			CurrencyPair: record.Currency1 + "/" + record.Currency2,
+			RateResponse: makeRateResponse(record.RateRecord),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		http.Error(w, "internal json problem", http.StatusInternalServerError)
+	}
+}
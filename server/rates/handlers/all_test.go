@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/artem98/ExchangeRateService/server/rates/db"
+	"github.com/shopspring/decimal"
+)
+
+func TestHandleGetAllRates(t *testing.T) {
+	updateTime := time.Date(2025, 3, 14, 10, 0, 0, 0, time.UTC)
+	calls := 0
+	handler := &Handler{
+		Db: &mockDb{
+			getRates: func(base string, pairs []db.CurrencyPair) ([]db.PairRateRecord, error) {
+				calls++
+				if base != "" || len(pairs) != 0 {
+					t.Errorf("expected no filter, got base %q and pairs %v", base, pairs)
+				}
+				return []db.PairRateRecord{
+					{
+						CurrencyPair: db.CurrencyPair{Currency1: "EUR", Currency2: "USD"},
+						RateRecord:   db.RateRecord{Rate: decimal.RequireFromString("1.0850"), UpdateTime: updateTime},
+					},
+					{
+						CurrencyPair: db.CurrencyPair{Currency1: "GBP", Currency2: "USD"},
+						RateRecord:   db.RateRecord{Rate: decimal.RequireFromString("1.27"), UpdateTime: updateTime},
+					},
+				}, nil
+			},
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/all", nil)
+	w := httptest.NewRecorder()
+	handler.handleGetAllRates(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if calls != 1 {
+		t.Errorf("expected a single database query, got %d", calls)
+	}
+
+	var resp AllRatesResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Rates) != 2 {
+		t.Fatalf("expected 2 rates, got %d", len(resp.Rates))
+	}
+	if resp.Rates[0].CurrencyPair != "EUR/USD" || resp.Rates[0].Rate != "1.0850" || !resp.Rates[0].Timestamp.Equal(updateTime) {
+		t.Errorf("unexpected first rate: %+v", resp.Rates[0])
+	}
+}
+
+func TestHandleGetAllRatesFilters(t *testing.T) {
+	var gotBase string
+	var gotPairs []db.CurrencyPair
+	handler := &Handler{
+		Db: &mockDb{
+			getRates: func(base string, pairs []db.CurrencyPair) ([]db.PairRateRecord, error) {
+				gotBase, gotPairs = base, pairs
+				return nil, nil
+			},
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/all?base=eur&currency_pairs=EUR/USD,eur/gbp&currency_pairs=EUR/MXN", nil)
+	w := httptest.NewRecorder()
+	handler.handleGetAllRates(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if gotBase != "EUR" {
+		t.Errorf("expected base EUR, got %q", gotBase)
+	}
+	expected := []db.CurrencyPair{
+		{Currency1: "EUR", Currency2: "USD"},
+		{Currency1: "EUR", Currency2: "GBP"},
+		{Currency1: "EUR", Currency2: "MXN"},
+	}
+	if !reflect.DeepEqual(gotPairs, expected) {
+		t.Errorf("expected pairs %v, got %v", expected, gotPairs)
+	}
+	if body := w.Body.String(); body != "{\"rates\":[]}\n" {
+		t.Errorf("expected empty rates list, got %s", body)
+	}
+}
+
+func TestHandleGetAllRatesBadParams(t *testing.T) {
+	handler := &Handler{
+		Db: &mockDb{
+			getRates: func(base string, pairs []db.CurrencyPair) ([]db.PairRateRecord, error) {
+				t.Error("database should not be queried")
+				return nil, nil
+			},
+		},
+	}
+
+	for _, target := range []string{"/all?base=EURO", "/all?currency_pairs=EURUSD", "/all?currency_pairs=EUR/USD,"} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		w := httptest.NewRecorder()
+		handler.handleGetAllRates(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, w.Code)
+		}
+	}
+}
+
+func TestHandleGetAllRatesDBError(t *testing.T) {
+	handler := &Handler{
+		Db: &mockDb{
+			getRates: func(base string, pairs []db.CurrencyPair) ([]db.PairRateRecord, error) {
+				return nil, errors.New("db down")
+			},
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/all", nil)
+	w := httptest.NewRecorder()
+	handler.handleGetAllRates(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", w.Code)
+	}
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/artem98/ExchangeRateService/server/constants"
+	"github.com/artem98/ExchangeRateService/server/rates/db"
+	"github.com/artem98/ExchangeRateService/server/rates/utils"
+)
+
+type BatchUpdateRequest struct {
+	CurrencyPairCodes []string `json:"pairs"`
+}
+
+type BatchUpdateResult struct {
+	CurrencyPairCode string `json:"pair"`
+	UpdateID         uint64 `json:"update_request_id,omitempty"`
+	Deduplicated     bool   `json:"deduplicated"`
+	Error            string `json:"error,omitempty"`
+}
+
+type BatchUpdateResponse struct {
+	Results []BatchUpdateResult `json:"results"`
+}
+
+func (h *Handler) handlePostRateUpdateRequestBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var batchRequest BatchUpdateRequest
+	err := json.NewDecoder(r.Body).Decode(&batchRequest)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(batchRequest.CurrencyPairCodes) == 0 {
+		http.Error(w, "pairs must not be empty", http.StatusBadRequest)
+		return
+	}
+	if len(batchRequest.CurrencyPairCodes) > constants.MaxBatchSize {
+		http.Error(w, fmt.Sprintf("at most %d pairs per batch", constants.MaxBatchSize), http.StatusBadRequest)
+		return
+	}
+
+	results := make([]BatchUpdateResult, len(batchRequest.CurrencyPairCodes))
+	firstIndex := make(map[db.CurrencyPair]int)
+	// Repeated pairs point at the position of their first occurrence and reuse its id.
+	duplicateOf := make(map[int]int)
+	var toPlace []db.CurrencyPair
+	var toPlaceIndex []int
+
+	for i, code := range batchRequest.CurrencyPairCodes {
+		results[i].CurrencyPairCode = code
+
+		currency1, currency2, err := utils.ParseCurrencyPair(code)
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		pair := db.CurrencyPair{Currency1: currency1, Currency2: currency2}
+		results[i].CurrencyPairCode = currency1 + "/" + currency2
+
+		if first, seen := firstIndex[pair]; seen {
+			results[i].Deduplicated = true
+			duplicateOf[i] = first
+			continue
+		}
+		firstIndex[pair] = i
+
+		if requestId, found := h.Cache.Get(currency1, currency2); found {
+			results[i].UpdateID = requestId
+			results[i].Deduplicated = true
+			continue
+		}
+
+		toPlace = append(toPlace, pair)
+		toPlaceIndex = append(toPlaceIndex, i)
+	}
+
+	if len(toPlace) > 0 {
+		ids, err := h.Db.PlaceRequests(toPlace, h.LeaseOwner)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// Once the queue is full the remaining pairs are rejected without waiting again.
+		var planErr error
+		for j, pair := range toPlace {
+			results[toPlaceIndex[j]].UpdateID = ids[j]
+			if planErr == nil {
+				request := db.UpdateRequestRecord{Id: ids[j], Currency1: pair.Currency1, Currency2: pair.Currency2}
+				planErr = h.planJob(request)
+			}
+			if planErr != nil {
+				results[toPlaceIndex[j]].Error = h.rejectRequest(ids[j], planErr).Error()
+				continue
+			}
+			h.Cache.Set(pair.Currency1, pair.Currency2, ids[j])
+		}
+		if planErr != nil {
+			setRetryAfter(w)
+		}
+	}
+
+	for i, first := range duplicateOf {
+		results[i].UpdateID = results[first].UpdateID
+		if results[i].Error == "" {
+			results[i].Error = results[first].Error
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(BatchUpdateResponse{Results: results})
+	if err != nil {
+		http.Error(w, "internal json problem", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/artem98/ExchangeRateService/server/rates/db"
+	"github.com/artem98/ExchangeRateService/server/rates/worker"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestHandlePostRateUpdateRequestBatch(t *testing.T) {
+	mockW := &mockWorker{}
+	var placed []db.CurrencyPair
+	var cached []string
+	handler := &Handler{
+		Db: &mockDb{
+			placeRequests: func(pairs []db.CurrencyPair) ([]uint64, error) {
+				placed = pairs
+				ids := make([]uint64, len(pairs))
+				for i := range pairs {
+					ids[i] = uint64(100 + i)
+				}
+				return ids, nil
+			},
+		},
+		Worker: mockW,
+		Cache: &mockCache{
+			get: func(currency1, currency2 string) (uint64, bool) {
+				if currency1 == "GBP" && currency2 == "USD" {
+					return 42, true
+				}
+				return 0, false
+			},
+			set: func(currency1, currency2 string, id uint64) {
+				cached = append(cached, currency1+currency2)
+			},
+		},
+	}
+
+	body := []byte(`{"pairs":["EUR/USD","GBP/USD","EURUSD","usd/mxn","EUR/USD"]}`)
+	req := httptest.NewRequest(http.MethodPost, "/update_requests:batch", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	handler.handlePostRateUpdateRequestBatch(w, req)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.StatusCode)
+	}
+
+	var resp BatchUpdateResponse
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	expected := []BatchUpdateResult{
+		{CurrencyPairCode: "EUR/USD", UpdateID: 100},
+		{CurrencyPairCode: "GBP/USD", UpdateID: 42, Deduplicated: true},
+		{CurrencyPairCode: "EURUSD"},
+		{CurrencyPairCode: "USD/MXN", UpdateID: 101},
+		{CurrencyPairCode: "EUR/USD", UpdateID: 100, Deduplicated: true},
+	}
+	if len(resp.Results) != len(expected) {
+		t.Fatalf("expected %d results, got %d", len(expected), len(resp.Results))
+	}
+	for i, result := range resp.Results {
+		if i == 2 {
+			if result.Error == "" || result.UpdateID != 0 {
+				t.Errorf("expected validation error for EURUSD, got %+v", result)
+			}
+			continue
+		}
+		if result != expected[i] {
+			t.Errorf("result %d: expected %+v, got %+v", i, expected[i], result)
+		}
+	}
+
+	if len(placed) != 2 || len(mockW.planned) != 2 || len(cached) != 2 {
+		t.Errorf("expected 2 new requests, got %d placed, %d planned, %d cached", len(placed), len(mockW.planned), len(cached))
+	}
+}
+
+func TestHandlePostRateUpdateRequestBatchQueueFull(t *testing.T) {
+	var rejected []uint64
+	handler := &Handler{
+		Db: &mockDb{
+			placeRequests: func(pairs []db.CurrencyPair) ([]uint64, error) {
+				return []uint64{100, 101}, nil
+			},
+			markRequestAsRejected: func(requestId uint64, reason string) error {
+				rejected = append(rejected, requestId)
+				return nil
+			},
+		},
+		Worker: &mockWorker{err: worker.ErrQueueFull},
+		Cache: &mockCache{
+			get: func(currency1, currency2 string) (uint64, bool) {
+				return 0, false
+			},
+			set: func(currency1, currency2 string, id uint64) {
+				t.Errorf("expected rejected requests not to be cached")
+			},
+		},
+	}
+
+	body := []byte(`{"pairs":["EUR/USD","USD/MXN","EUR/USD"]}`)
+	req := httptest.NewRequest(http.MethodPost, "/update_requests:batch", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	handler.handlePostRateUpdateRequestBatch(w, req)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	if res.Header.Get("Retry-After") == "" {
+		t.Errorf("expected Retry-After header")
+	}
+	var resp BatchUpdateResponse
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	for i, result := range resp.Results {
+		if result.Error == "" || result.UpdateID == 0 {
+			t.Errorf("result %d: expected rejected request with its id, got %+v", i, result)
+		}
+	}
+	if len(rejected) != 2 {
+		t.Errorf("expected both placed requests to be rejected, got %v", rejected)
+	}
+}
+
+func TestHandlePostRateUpdateRequestBatchAllCached(t *testing.T) {
+	mockW := &mockWorker{}
+	handler := &Handler{
+		Db:     &mockDb{},
+		Worker: mockW,
+		Cache: &mockCache{
+			get: func(currency1, currency2 string) (uint64, bool) {
+				return 7, true
+			},
+		},
+	}
+
+	body := []byte(`{"pairs":["EUR/USD"]}`)
+	req := httptest.NewRequest(http.MethodPost, "/update_requests:batch", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	handler.handlePostRateUpdateRequestBatch(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", w.Code)
+	}
+	if len(mockW.planned) != 0 {
+		t.Errorf("expected no job to be planned")
+	}
+}
+
+func TestHandlePostRateUpdateRequestBatchBadBody(t *testing.T) {
+	handler := &Handler{
+		Db:     &mockDb{},
+		Worker: &mockWorker{},
+		Cache:  &mockCache{},
+	}
+
+	bodies := []string{
+		`{"pairs":`,
+		`{"pairs":[]}`,
+		`{"pairs":["` + strings.Repeat(`EUR/USD","`, 100) + `EUR/USD"]}`,
+	}
+	for _, body := range bodies {
+		req := httptest.NewRequest(http.MethodPost, "/update_requests:batch", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		handler.handlePostRateUpdateRequestBatch(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", w.Code)
+		}
+	}
+}
+
+func TestHandlePostRateUpdateRequestBatchNotJson(t *testing.T) {
+	handler := &Handler{
+		Db:     &mockDb{},
+		Worker: &mockWorker{},
+		Cache:  &mockCache{},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/update_requests:batch", strings.NewReader(`{"pairs":["EUR/USD"]}`))
+	w := httptest.NewRecorder()
+	handler.handlePostRateUpdateRequestBatch(w, req)
+
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("expected 415, got %d", w.Code)
+	}
+}
+
+func TestHandlePostRateUpdateRequestBatchDBError(t *testing.T) {
+	mockW := &mockWorker{}
+	handler := &Handler{
+		Db: &mockDb{
+			placeRequests: func(pairs []db.CurrencyPair) ([]uint64, error) {
+				return nil, errors.New("db down")
+			},
+		},
+		Worker: mockW,
+		Cache: &mockCache{
+			get: func(currency1, currency2 string) (uint64, bool) {
+				return 0, false
+			},
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/update_requests:batch", strings.NewReader(`{"pairs":["EUR/USD"]}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	handler.handlePostRateUpdateRequestBatch(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", w.Code)
+	}
+	if len(mockW.planned) != 0 {
+		t.Errorf("expected no job to be planned")
+	}
+}
+
+func TestHandleRatesRoutesBatch(t *testing.T) {
+	handler := &Handler{
+		Db: &mockDb{
+			placeRequests: func(pairs []db.CurrencyPair) ([]uint64, error) {
+				return []uint64{1}, nil
+			},
+		},
+		Worker: &mockWorker{},
+		Cache: &mockCache{
+			get: func(currency1, currency2 string) (uint64, bool) { return 0, false },
+			set: func(currency1, currency2 string, id uint64) {},
+		},
+	}
+
+	r := chi.NewRouter()
+	r.Route("/rates", handler.HandleRates)
+
+	req := httptest.NewRequest(http.MethodPost, "/rates/update_requests:batch", strings.NewReader(`{"pairs":["EUR/USD"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"update_request_id":1`) {
+		t.Errorf("unexpected response body: %s", w.Body.String())
+	}
+}
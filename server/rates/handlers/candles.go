@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/artem98/ExchangeRateService/server/constants"
+	"github.com/artem98/ExchangeRateService/server/rates/utils"
+)
+
+var candleIntervals = map[string]time.Duration{
+	"1m":  time.Minute,
+	"5m":  5 * time.Minute,
+	"15m": 15 * time.Minute,
+	"30m": 30 * time.Minute,
+	"1h":  time.Hour,
+	"4h":  4 * time.Hour,
+	"1d":  24 * time.Hour,
+}
+
+type CandleResponse struct {
+	Start   time.Time   `json:"start"`
+	Open    json.Number `json:"open"`
+	High    json.Number `json:"high"`
+	Low     json.Number `json:"low"`
+	Close   json.Number `json:"close"`
+	Samples int         `json:"samples"`
+}
+
+type CandlesResponse struct {
+	CurrencyPair string           `json:"currency_pair"`
+	Interval     string           `json:"interval"`
+	Candles      []CandleResponse `json:"candles"`
+}
+
+func (h *Handler) handleGetRateCandles(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	currencyPair := query.Get("currency_pair")
+	if currencyPair == "" {
+		http.Error(w, "currency_pair query parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	currency1, currency2, err := utils.ParseCurrencyPair(currencyPair)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	intervalParam := query.Get("interval")
+	if intervalParam == "" {
+		intervalParam = "1h"
+	}
+	interval, ok := candleIntervals[intervalParam]
+	if !ok {
+		http.Error(w, "interval must be one of 1m, 5m, 15m, 30m, 1h, 4h, 1d", http.StatusBadRequest)
+		return
+	}
+
+	to, err := parseTimeParam(query.Get("to"), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	from, err := parseTimeParam(query.Get("from"), to.Add(-constants.DefaultCandleCount*interval))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !from.Before(to) {
+		http.Error(w, "from must be before to", http.StatusBadRequest)
+		return
+	}
+	if to.Sub(from)/interval > constants.MaxCandleCount {
+		http.Error(w, fmt.Sprintf("range covers more than %d candles, use a larger interval", constants.MaxCandleCount),
+			http.StatusBadRequest)
+		return
+	}
+
+	// Times are stored without a time zone, in UTC.
+	candles, err := h.Db.GetRateCandles(currency1, currency2, interval, from.UTC(), to.UTC())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := CandlesResponse{
+		CurrencyPair: currency1 + "/" + currency2,
+		Interval:     intervalParam,
+		Candles:      make([]CandleResponse, 0, len(candles)),
+	}
+	for _, c := range candles {
+		response.Candles = append(response.Candles, CandleResponse{
+			Start:   c.Start,
+			Open:    decimalNumber(c.Open),
+			High:    decimalNumber(c.High),
+			Low:     decimalNumber(c.Low),
+			Close:   decimalNumber(c.Close),
+			Samples: c.Samples,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		http.Error(w, "internal json problem", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/artem98/ExchangeRateService/server/rates/db"
+	"github.com/shopspring/decimal"
+)
+
+func TestHandleGetRateCandles(t *testing.T) {
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	var gotInterval time.Duration
+	handler := &Handler{
+		Db: &mockDb{
+			getRateCandles: func(cur1, cur2 string, interval time.Duration, from, to time.Time) ([]db.Candle, error) {
+				gotInterval = interval
+				return []db.Candle{
+					{Start: start, Open: decimal.RequireFromString("1.1"), High: decimal.RequireFromString("1.3"), Low: decimal.RequireFromString("1.0"), Close: decimal.RequireFromString("1.2"), Samples: 4},
+					{Start: start.Add(time.Hour), Open: decimal.RequireFromString("1.2"), High: decimal.RequireFromString("1.2"), Low: decimal.RequireFromString("1.2"), Close: decimal.RequireFromString("1.2"), Samples: 1},
+				}, nil
+			},
+		},
+		Worker: &mockWorker{},
+		Cache:  &mockCache{},
+	}
+
+	req := httptest.NewRequest(http.MethodGet,
+		"/candles?currency_pair=EUR/USD&interval=1h&from=2025-01-01&to=2025-01-02", nil)
+	w := httptest.NewRecorder()
+
+	handler.handleGetRateCandles(w, req)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.StatusCode)
+	}
+	if gotInterval != time.Hour {
+		t.Errorf("expected 1h interval, got %v", gotInterval)
+	}
+
+	var resp CandlesResponse
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Candles) != 2 {
+		t.Fatalf("expected 2 candles, got %d", len(resp.Candles))
+	}
+	first := resp.Candles[0]
+	if first.Open != "1.1" || first.High != "1.3" || first.Low != "1.0" || first.Close != "1.2" || first.Samples != 4 {
+		t.Errorf("unexpected first candle %+v", first)
+	}
+}
+
+func TestHandleGetRateCandlesWithOffset(t *testing.T) {
+	var gotFrom, gotTo time.Time
+	handler := &Handler{
+		Db: &mockDb{
+			getRateCandles: func(cur1, cur2 string, interval time.Duration, from, to time.Time) ([]db.Candle, error) {
+				gotFrom, gotTo = from, to
+				return nil, nil
+			},
+		},
+		Worker: &mockWorker{},
+		Cache:  &mockCache{},
+	}
+
+	req := httptest.NewRequest(http.MethodGet,
+		"/candles?currency_pair=EUR/USD&interval=1h&from=2025-01-01T00:00:00%2B03:00&to=2025-01-01T12:00:00%2B03:00", nil)
+	w := httptest.NewRecorder()
+	handler.handleGetRateCandles(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	wantFrom := time.Date(2024, 12, 31, 21, 0, 0, 0, time.UTC)
+	if gotFrom != wantFrom || gotTo != wantFrom.Add(12*time.Hour) {
+		t.Errorf("expected candles from %v, got %v - %v", wantFrom, gotFrom, gotTo)
+	}
+}
+
+func TestHandleGetRateCandlesBadParams(t *testing.T) {
+	handler := &Handler{
+		Db:     &mockDb{},
+		Worker: &mockWorker{},
+		Cache:  &mockCache{},
+	}
+
+	urls := []string{
+		"/candles",
+		"/candles?currency_pair=EUR/USD&interval=7m",
+		"/candles?currency_pair=EUR/USD&from=2025-01-02&to=2025-01-01",
+		"/candles?currency_pair=EUR/USD&interval=1m&from=2024-01-01&to=2025-01-01",
+	}
+	for _, url := range urls {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		w := httptest.NewRecorder()
+
+		handler.handleGetRateCandles(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", url, w.Code)
+		}
+	}
+}
+
+func TestHandleGetRateCandlesDBError(t *testing.T) {
+	handler := &Handler{
+		Db: &mockDb{
+			getRateCandles: func(cur1, cur2 string, interval time.Duration, from, to time.Time) ([]db.Candle, error) {
+				return nil, errors.New("db down")
+			},
+		},
+		Worker: &mockWorker{},
+		Cache:  &mockCache{},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/candles?currency_pair=EUR/USD", nil)
+	w := httptest.NewRecorder()
+
+	handler.handleGetRateCandles(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", w.Code)
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/artem98/ExchangeRateService/server/constants"
+	"github.com/artem98/ExchangeRateService/server/rates/db"
+	"github.com/artem98/ExchangeRateService/server/rates/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+type ConvertResponse struct {
+	From            string             `json:"from"`
+	To              string             `json:"to"`
+	Amount          json.Number        `json:"amount"`
+	ConvertedAmount json.Number        `json:"converted_amount"`
+	Rate            json.Number        `json:"rate"`
+	Timestamp       time.Time          `json:"update_time"`
+	Rounding        utils.RoundingMode `json:"rounding"`
+}
+
+func (h *Handler) HandleConvert(r chi.Router) {
+	r.Get("/", handlerWithMiddleware(h.handleConvert))
+	r.MethodNotAllowed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Only GET is allowed", http.StatusMethodNotAllowed)
+	}))
+}
+
+func (h *Handler) handleConvert(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("from") == "" || query.Get("to") == "" || query.Get("amount") == "" {
+		http.Error(w, "from, to and amount query parameters are required", http.StatusBadRequest)
+		return
+	}
+
+	from, err := utils.ParseCurrencyCode(query.Get("from"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	to, err := utils.ParseCurrencyCode(query.Get("to"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	amount, err := utils.ParseAmount(query.Get("amount"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rounding := utils.RoundingMode(constants.DefaultRoundingMode)
+	if roundingParam := query.Get("rounding"); roundingParam != "" {
+		rounding, err = utils.ParseRoundingMode(roundingParam)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	record, _, err := h.ResolveRate(from, to)
+	if err != nil {
+		if errors.Is(err, db.ErrNoSuchPair) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	minorUnits := utils.MinorUnits(to)
+	converted := utils.Round(amount.Mul(record.Rate), minorUnits, rounding)
+
+	response := ConvertResponse{
+		From:            from,
+		To:              to,
+		Amount:          decimalNumber(amount),
+		ConvertedAmount: json.Number(converted.StringFixed(minorUnits)),
+		Rate:            decimalNumber(record.Rate),
+		Timestamp:       record.UpdateTime,
+		Rounding:        rounding,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		http.Error(w, "internal json problem", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/artem98/ExchangeRateService/server/rates/db"
+	"github.com/artem98/ExchangeRateService/server/rates/utils"
+	"github.com/shopspring/decimal"
+)
+
+func makeConvertHandler() *Handler {
+	return &Handler{
+		Db: &mockDb{
+			getByPair: func(cur1, cur2 string) (db.RateRecord, error) {
+				switch cur1 + "/" + cur2 {
+				case "EUR/USD":
+					return db.RateRecord{Rate: decimal.RequireFromString("1.0845"), UpdateTime: time.Now()}, nil
+				case "EUR/JPY":
+					return db.RateRecord{Rate: decimal.RequireFromString("161.5"), UpdateTime: time.Now()}, nil
+				}
+				return db.RateRecord{}, db.ErrNoSuchPair
+			},
+		},
+		Worker: &mockWorker{},
+		Cache:  &mockCache{},
+	}
+}
+
+func TestHandleConvert(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/convert?from=eur&to=USD&amount=125.50", nil)
+	w := httptest.NewRecorder()
+
+	makeConvertHandler().handleConvert(w, req)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.StatusCode)
+	}
+
+	var resp ConvertResponse
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.ConvertedAmount != "136.10" {
+		t.Errorf("expected 136.10, got %v", resp.ConvertedAmount)
+	}
+	if resp.From != "EUR" || resp.To != "USD" || resp.Rate != "1.0845" || resp.Rounding != utils.RoundHalfEven {
+		t.Errorf("unexpected response %+v", resp)
+	}
+}
+
+func TestHandleConvertMinorUnitsAndRounding(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/convert?from=EUR&to=JPY&amount=10.01&rounding=down", nil)
+	w := httptest.NewRecorder()
+
+	makeConvertHandler().handleConvert(w, req)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	var resp ConvertResponse
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.ConvertedAmount != "1616" {
+		t.Errorf("expected 1616, got %v", resp.ConvertedAmount)
+	}
+}
+
+func TestHandleConvertBadParams(t *testing.T) {
+	urls := []string{
+		"/convert?from=EUR&to=USD",
+		"/convert?from=EURO&to=USD&amount=1",
+		"/convert?from=EUR&to=USD&amount=ten",
+		"/convert?from=EUR&to=USD&amount=1e20000000",
+		"/convert?from=EUR&to=USD&amount=1&rounding=ceiling",
+	}
+	for _, url := range urls {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		w := httptest.NewRecorder()
+
+		makeConvertHandler().handleConvert(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", url, w.Code)
+		}
+	}
+}
+
+func TestHandleConvertUnknownPair(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/convert?from=GBP&to=MXN&amount=1", nil)
+	w := httptest.NewRecorder()
+
+	makeConvertHandler().handleConvert(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestHandleConvertDBError(t *testing.T) {
+	handler := &Handler{
+		Db: &mockDb{
+			getByPair: func(cur1, cur2 string) (db.RateRecord, error) {
+				return db.RateRecord{}, errors.New("db down")
+			},
+		},
+		Worker: &mockWorker{},
+		Cache:  &mockCache{},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/convert?from=EUR&to=USD&amount=1", nil)
+	w := httptest.NewRecorder()
+
+	handler.handleConvert(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", w.Code)
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/artem98/ExchangeRateService/server/constants"
+	"github.com/artem98/ExchangeRateService/server/rates/db"
+	"github.com/artem98/ExchangeRateService/server/rates/events"
+)
+
+// parseDurationParam accepts a Go duration such as "90s" or "5m", or a plain
+// number of seconds.
+func parseDurationParam(name, value string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, fmt.Errorf("%s must not be negative", name)
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%s must be a number of seconds or a duration like 90s", name)
+	}
+	return d, nil
+}
+
+// awaitRefresh requests an update of the pair and waits until a rate of the
+// pair younger than maxAge is published, the request finishes, wait has passed
+// or ctx is done. Older rates published meanwhile, such as those of updates
+// placed before, do not end the wait. It returns the id of the update request,
+// or 0 when it could not be placed.
+func (h *Handler) awaitRefresh(ctx context.Context, currency1, currency2 string, maxAge, wait time.Duration) uint64 {
+	var rates *events.Subscription
+	var requests *events.RequestSubscription
+	// Subscribe first, so an update finishing right away is not missed.
+	if h.Events != nil && wait > 0 {
+		var err error
+		rates, _, err = h.Events.Subscribe([]db.CurrencyPair{{Currency1: currency1, Currency2: currency2}}, 0)
+		if err != nil {
+			fmt.Println("Failed to wait for the refresh of a stale rate:", err)
+		} else {
+			requests = h.Events.SubscribeRequests()
+			defer func() {
+				h.Events.Unsubscribe(rates)
+				h.Events.UnsubscribeRequests(requests)
+			}()
+		}
+	}
+
+	requestId, deduplicated, err := h.RequestUpdate(currency1, currency2, "")
+	if err != nil {
+		fmt.Println("Failed to refresh stale rate:", err)
+		return 0
+	}
+	if rates == nil {
+		return requestId
+	}
+	if deduplicated {
+		// The recent request may have finished before the subscription.
+		if record, err := h.Db.GetUpdateRequest(requestId); err != nil || record.Status != db.RequestSubmitted {
+			return requestId
+		}
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		select {
+		case event, ok := <-rates.C:
+			if !ok || time.Since(event.Record.UpdateTime) <= maxAge {
+				return requestId
+			}
+		case event, ok := <-requests.C:
+			if !ok || event.RequestId == requestId {
+				return requestId
+			}
+		case <-timer.C:
+			return requestId
+		case <-ctx.Done():
+			return requestId
+		}
+	}
+}
+
+// refreshWait reads the wait parameter of a stale read, which is capped by
+// constants.MaxRefreshWait.
+func refreshWait(value string) (time.Duration, error) {
+	if value == "" {
+		return constants.DefaultRefreshWait, nil
+	}
+	wait, err := parseDurationParam("wait", value)
+	if err != nil {
+		return 0, err
+	}
+	return min(wait, constants.MaxRefreshWait), nil
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/artem98/ExchangeRateService/server/rates/db"
+	"github.com/shopspring/decimal"
+)
+
+// freshnessHandler serves EUR/USD stored at updated. Placing an update request
+// calls onPlace, which may store a newer rate.
+func freshnessHandler(t *testing.T, updated time.Time, onPlace func(store func(db.RateRecord))) (*Handler, *mockWorker) {
+	var mu sync.Mutex
+	stored := db.RateRecord{Rate: decimal.RequireFromString("1.1"), UpdateTime: updated}
+	broker, publish := makeBroker(t)
+	store := func(record db.RateRecord) {
+		mu.Lock()
+		stored = record
+		mu.Unlock()
+		publish("EUR", "USD", record)
+	}
+
+	mockW := &mockWorker{}
+	handler := &Handler{
+		Db: &mockDb{
+			getByPair: func(cur1, cur2 string) (db.RateRecord, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				return stored, nil
+			},
+			placeRequest: func(cur1, cur2, callbackUrl string) (uint64, error) {
+				if onPlace != nil {
+					onPlace(store)
+				}
+				return 55, nil
+			},
+		},
+		Worker: mockW,
+		Cache: &mockCache{
+			get: func(currency1, currency2 string) (uint64, bool) { return 0, false },
+			set: func(currency1, currency2 string, id uint64) {},
+		},
+		Events: broker,
+	}
+	return handler, mockW
+}
+
+func getRate(t *testing.T, handler *Handler, query string) RateResponse {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/?currency_pair=EUR/USD&"+query, nil)
+	w := httptest.NewRecorder()
+	handler.handleGetRateByCode(w, req)
+
+	res := w.Result()
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.StatusCode)
+	}
+	var resp RateResponse
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return resp
+}
+
+func TestHandleGetRateByCodeFreshEnough(t *testing.T) {
+	handler, mockW := freshnessHandler(t, time.Now().Add(-10*time.Second), nil)
+
+	resp := getRate(t, handler, "max_age=1m")
+	if resp.Stale || resp.AgeSeconds == nil || *resp.AgeSeconds < 10 {
+		t.Errorf("expected fresh rate with its age, got %+v", resp)
+	}
+	if len(mockW.planned) != 0 {
+		t.Errorf("expected no refresh")
+	}
+}
+
+func TestHandleGetRateByCodeStaleWithoutWaiting(t *testing.T) {
+	handler, mockW := freshnessHandler(t, time.Now().Add(-time.Hour), nil)
+
+	resp := getRate(t, handler, "max_age=60&wait=0")
+	if !resp.Stale || resp.AgeSeconds == nil || *resp.AgeSeconds < 3600 {
+		t.Errorf("expected stale rate with its age, got %+v", resp)
+	}
+	if resp.UpdateID != 55 || len(mockW.planned) != 1 {
+		t.Errorf("expected refresh 55 to be planned, got %d", resp.UpdateID)
+	}
+}
+
+func TestHandleGetRateByCodeWaitsForRefresh(t *testing.T) {
+	var handler *Handler
+	handler, _ = freshnessHandler(t, time.Now().Add(-time.Hour), func(store func(db.RateRecord)) {
+		go func() {
+			fresh := db.RateRecord{Rate: decimal.RequireFromString("1.2"), UpdateTime: time.Now()}
+			store(fresh)
+		}()
+	})
+
+	start := time.Now()
+	resp := getRate(t, handler, "max_age=1m&wait=10s")
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("expected the refresh to end the wait")
+	}
+	if resp.Stale || resp.Rate != "1.2" {
+		t.Errorf("expected refreshed rate, got %+v", resp)
+	}
+}
+
+func TestHandleGetRateByCodeWaitsPastOlderRates(t *testing.T) {
+	var handler *Handler
+	handler, _ = freshnessHandler(t, time.Now().Add(-time.Hour), func(store func(db.RateRecord)) {
+		go func() {
+			// An earlier update publishes a rate that is still too old.
+			store(db.RateRecord{Rate: decimal.RequireFromString("1.15"), UpdateTime: time.Now().Add(-30 * time.Minute)})
+			time.Sleep(200 * time.Millisecond)
+			store(db.RateRecord{Rate: decimal.RequireFromString("1.2"), UpdateTime: time.Now()})
+		}()
+	})
+
+	resp := getRate(t, handler, "max_age=1m&wait=10s")
+	if resp.Stale || resp.Rate != "1.2" {
+		t.Errorf("expected the rate younger than max_age, got %+v", resp)
+	}
+}
+
+func TestHandleGetRateByCodeInvalidMaxAge(t *testing.T) {
+	handler, _ := freshnessHandler(t, time.Now(), nil)
+
+	for _, query := range []string{"max_age=soon", "max_age=-5", "max_age=1m&wait=later"} {
+		req := httptest.NewRequest(http.MethodGet, "/?currency_pair=EUR/USD&"+query, nil)
+		w := httptest.NewRecorder()
+		handler.handleGetRateByCode(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+}
@@ -1,20 +1,30 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/artem98/ExchangeRateService/server/constants"
 	"github.com/artem98/ExchangeRateService/server/rates/db"
+	"github.com/artem98/ExchangeRateService/server/rates/events"
+	"github.com/artem98/ExchangeRateService/server/rates/external"
+	"github.com/artem98/ExchangeRateService/server/rates/triangulation"
 	"github.com/artem98/ExchangeRateService/server/rates/utils"
+	"github.com/artem98/ExchangeRateService/server/rates/webhooks"
 	"github.com/artem98/ExchangeRateService/server/rates/worker"
 	"github.com/go-chi/chi/v5"
+	"github.com/shopspring/decimal"
 )
 
 type UpdateRequest struct {
 	CurrencyPairCode string `json:"pair"`
+	CallbackUrl      string `json:"callback_url,omitempty"`
 }
 
 type UpdateResponse struct {
@@ -22,12 +32,85 @@ type UpdateResponse struct {
 }
 
 type RateResponse struct {
-	Rate      float64   `json:"rate"`
-	Timestamp time.Time `json:"update_time"`
+	Rate       json.Number   `json:"rate"`
+	Timestamp  time.Time     `json:"update_time"`
+	Provider   string        `json:"provider"`
+	Sources    []string      `json:"sources"`
+	Spread     json.Number   `json:"spread"`
+	AsOf       *time.Time    `json:"as_of,omitempty"`
+	DerivedVia []LegResponse `json:"derived_via,omitempty"`
+	// Only set when the rate was read with max_age.
+	AgeSeconds *int64 `json:"age_seconds,omitempty"`
+	Stale      bool   `json:"stale,omitempty"`
+	UpdateID   uint64 `json:"update_request_id,omitempty"`
+}
+
+// UpdateStatusResponse reports the state of an update request. The rate fields
+// are filled once the request is ok and hold the rate this request stored,
+// Error is only set when the request failed.
+type UpdateStatusResponse struct {
+	UpdateID    uint64               `json:"update_request_id"`
+	Pair        string               `json:"pair"`
+	Status      db.RequestStatus     `json:"status"`
+	CreatedAt   time.Time            `json:"created_at"`
+	CompletedAt *time.Time           `json:"completed_at,omitempty"`
+	Attempts    int                  `json:"attempts"`
+	LastError   string               `json:"last_error,omitempty"`
+	Error       *UpdateErrorResponse `json:"error,omitempty"`
+	*RateResponse
+}
+
+type UpdateErrorResponse struct {
+	Reason string `json:"reason"`
+}
+
+func makeUpdateStatusResponse(record db.UpdateRequestRecord) UpdateStatusResponse {
+	response := UpdateStatusResponse{
+		UpdateID:  record.Id,
+		Pair:      record.Currency1 + "/" + record.Currency2,
+		Status:    record.Status,
+		CreatedAt: record.CreatedAt,
+		Attempts:  record.Attempts,
+		LastError: record.LastError,
+	}
+	if !record.CompletedAt.IsZero() {
+		response.CompletedAt = &record.CompletedAt
+	}
+	switch record.Status {
+	case db.RequestOk:
+		rateResponse := makeRateResponse(record.Rate)
+		response.RateResponse = &rateResponse
+	case db.RequestFailed, db.RequestRejected:
+		response.Error = &UpdateErrorResponse{Reason: record.FailureReason}
+	}
+	return response
+}
+
+// LegResponse describes a stored rate a derived rate was calculated from.
+type LegResponse struct {
+	Pair      string      `json:"pair"`
+	Inverse   bool        `json:"inverse"`
+	Rate      json.Number `json:"rate"`
+	Timestamp time.Time   `json:"update_time"`
+}
+
+func makeRateResponse(record db.RateRecord) RateResponse {
+	return RateResponse{
+		Rate:      decimalNumber(record.Rate),
+		Timestamp: record.UpdateTime,
+		Provider:  record.Provider,
+		Sources:   record.Sources,
+		Spread:    decimalNumber(record.Spread),
+	}
+}
+
+// decimalNumber encodes d as a JSON number with exactly the digits it was stored with.
+func decimalNumber(d decimal.Decimal) json.Number {
+	return json.Number(utils.FormatDecimal(d))
 }
 
 type Worker interface {
-	PlanJob(job worker.Job)
+	PlanJob(job worker.Job) error
 }
 
 type RateJobsCache interface {
@@ -35,10 +118,32 @@ type RateJobsCache interface {
 	Set(currency1, currency2 string, id uint64)
 }
 
+// RateEvents publishes stored rates and finished update requests to their subscribers.
+type RateEvents interface {
+	RatesStored()
+	PublishRequest(event events.RequestEvent)
+	Subscribe(pairs []db.CurrencyPair, lastEventId uint64) (*events.Subscription, []events.RateEvent, error)
+	Unsubscribe(s *events.Subscription)
+	SubscribeRequests() *events.RequestSubscription
+	UnsubscribeRequests(s *events.RequestSubscription)
+}
+
 type Handler struct {
-	Db     db.DataBase
-	Worker Worker
-	Cache  RateJobsCache
+	Db               db.DataBase
+	Worker           Worker
+	Cache            RateJobsCache
+	Provider         external.RateProvider
+	Pivots           []string
+	Events           RateEvents
+	CallbacksEnabled bool
+	// Resolver looks up the hosts of callback URLs, net.DefaultResolver when nil.
+	Resolver webhooks.Resolver
+	// LeaseOwner identifies this server in the leases of the update requests it places.
+	LeaseOwner string
+	Retry      worker.RetryPolicy
+	// ShuttingDown is closed when the server starts to shut down. Streams and
+	// WebSockets end then, other requests are left to finish.
+	ShuttingDown <-chan struct{}
 }
 
 func (h *Handler) HandleRates(r chi.Router) {
@@ -49,6 +154,11 @@ func (h *Handler) HandleRates(r chi.Router) {
 			http.Error(w, "Only POST and GET are allowed", http.StatusMethodNotAllowed)
 		}))
 	})
+	r.Post("/update_requests:batch", handlerWithMiddleware(h.handlePostRateUpdateRequestBatch))
+	r.Get("/history", handlerWithMiddleware(h.handleGetRateHistory))
+	r.Get("/candles", handlerWithMiddleware(h.handleGetRateCandles))
+	r.Get("/all", handlerWithMiddleware(h.handleGetAllRates))
+	r.Get("/stream", handlerWithMiddleware(h.handleRateStream))
 	r.Get("/", handlerWithMiddleware(h.handleGetRateByCode))
 	r.MethodNotAllowed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Only GET is allowed", http.StatusMethodNotAllowed)
@@ -68,14 +178,101 @@ func (h *Handler) handleGetRateByCode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rate, timestamp, err := h.Db.GetRateByPair(currency1, currency2)
+	if asOfParam := r.URL.Query().Get("as_of"); asOfParam != "" {
+		h.writeRateAsOf(w, currency1, currency2, asOfParam)
+		return
+	}
+
+	var maxAge, wait time.Duration
+	maxAgeParam := r.URL.Query().Get("max_age")
+	if maxAgeParam != "" {
+		if maxAge, err = parseDurationParam("max_age", maxAgeParam); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if wait, err = refreshWait(r.URL.Query().Get("wait")); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	record, legs, err := h.ResolveRate(currency1, currency2)
+	if err != nil {
+		if errors.Is(err, db.ErrNoSuchPair) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// A rate older than max_age is refreshed. When the refresh does not finish
+	// within wait, the stored rate is returned flagged as stale.
+	var refreshId uint64
+	if maxAgeParam != "" && time.Since(record.UpdateTime) > maxAge {
+		refreshId = h.awaitRefresh(r.Context(), currency1, currency2, maxAge, wait)
+		if refreshed, refreshedLegs, err := h.ResolveRate(currency1, currency2); err == nil {
+			record, legs = refreshed, refreshedLegs
+		}
+	}
+
+	response := makeRateResponse(record)
+	if maxAgeParam != "" {
+		age := time.Since(record.UpdateTime)
+		ageSeconds := int64(age.Seconds())
+		response.AgeSeconds = &ageSeconds
+		response.Stale = age > maxAge
+		if response.Stale {
+			response.UpdateID = refreshId
+		}
+	}
+	for _, leg := range legs {
+		response.DerivedVia = append(response.DerivedVia, LegResponse{
+			Pair:      leg.Currency1 + "/" + leg.Currency2,
+			Inverse:   leg.Inverse,
+			Rate:      decimalNumber(leg.Record.Rate),
+			Timestamp: leg.Record.UpdateTime,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		http.Error(w, "internal json problem", http.StatusInternalServerError)
+	}
+}
+
+// ResolveRate returns the stored rate of the pair or, when there is none, derives
+// it from other stored rates.
+func (h *Handler) ResolveRate(currency1, currency2 string) (db.RateRecord, []triangulation.Leg, error) {
+	t := triangulation.Triangulator{Source: h.Db, Pivots: h.Pivots}
+	return t.Resolve(currency1, currency2)
+}
+
+func (h *Handler) writeRateAsOf(w http.ResponseWriter, currency1, currency2, asOfParam string) {
+	asOf, err := utils.ParseTimestamp(asOfParam)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Times are stored without a time zone, in UTC.
+	asOf = asOf.UTC()
+
+	record, err := h.Db.GetRateAsOf(currency1, currency2, asOf)
 	if err != nil {
+		if errors.Is(err, db.ErrNoRateAtTime) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	response := makeRateResponse(record)
+	response.AsOf = &asOf
+
 	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(RateResponse{Rate: rate, Timestamp: timestamp})
+	err = json.NewEncoder(w).Encode(response)
 	if err != nil {
 		http.Error(w, "internal json problem", http.StatusInternalServerError)
 	}
@@ -96,13 +293,24 @@ func (h *Handler) handleGetRateByUpdateId(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	rate, timestamp, err := h.Db.GetRateByRequestId(id)
+	record, err := h.Db.GetUpdateRequest(id)
 	if err != nil {
+		if errors.Is(err, db.ErrNoSuchRequest) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+
+	statusCode := http.StatusOK
+	if record.Status == db.RequestSubmitted {
+		statusCode = http.StatusAccepted
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(RateResponse{Rate: rate, Timestamp: timestamp})
+	w.WriteHeader(statusCode)
+	err = json.NewEncoder(w).Encode(makeUpdateStatusResponse(record))
 	if err != nil {
 		http.Error(w, "internal json problem", http.StatusInternalServerError)
 	}
@@ -135,27 +343,24 @@ func (h *Handler) handlePostRateUpdateRequest(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	requestId, found := h.Cache.Get(currency1, currency2)
-	if found {
-		fmt.Println("Found cached recent request")
-		response := UpdateResponse{UpdateID: requestId}
-		w.Header().Set("Content-Type", "application/json")
-		err = json.NewEncoder(w).Encode(response)
-		if err != nil {
-			http.Error(w, "internal json problem", http.StatusInternalServerError)
+	if updateRequest.CallbackUrl != "" {
+		if err := h.ValidateCallbackUrl(r.Context(), updateRequest.CallbackUrl); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
-		return
 	}
 
-	requestId, err = h.Db.PlaceRequest(currency1, currency2)
+	requestId, _, err := h.RequestUpdate(currency1, currency2, updateRequest.CallbackUrl)
+	if errors.Is(err, worker.ErrQueueFull) {
+		setRetryAfter(w)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	h.Worker.PlanJob(worker.MakeRateUpdateJob(currency1, currency2, requestId, h.Db))
-	h.Cache.Set(currency1, currency2, requestId)
-
 	response := UpdateResponse{UpdateID: requestId}
 	w.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(w).Encode(response)
@@ -163,3 +368,69 @@ func (h *Handler) handlePostRateUpdateRequest(w http.ResponseWriter, r *http.Req
 		http.Error(w, "internal json problem", http.StatusInternalServerError)
 	}
 }
+
+// RequestUpdate plans an update of the pair, or returns the id of a recent
+// request for it when there is one. callbackUrl must have been validated.
+func (h *Handler) RequestUpdate(currency1, currency2, callbackUrl string) (requestId uint64, deduplicated bool, err error) {
+	// A recent request may already have finished without calling anyone back,
+	// so requests with a callback always get their own update.
+	if callbackUrl == "" {
+		if requestId, found := h.Cache.Get(currency1, currency2); found {
+			fmt.Println("Found cached recent request")
+			return requestId, true, nil
+		}
+	}
+
+	requestId, err = h.Db.PlaceRequest(currency1, currency2, callbackUrl, h.LeaseOwner)
+	if err != nil {
+		return 0, false, err
+	}
+
+	err = h.planJob(db.UpdateRequestRecord{Id: requestId, Currency1: currency1, Currency2: currency2})
+	if err != nil {
+		return 0, false, h.rejectRequest(requestId, err)
+	}
+	h.Cache.Set(currency1, currency2, requestId)
+	return requestId, false, nil
+}
+
+// planJob hands the update of a stored request to the worker. A request the
+// stopping worker refuses gives up its lease and stays submitted, so the queue
+// of another server or of the next start takes it, and is accepted all the same.
+func (h *Handler) planJob(request db.UpdateRequestRecord) error {
+	err := h.Worker.PlanJob(worker.MakeRateUpdateJob(request, h.LeaseOwner, h.Retry, h.Db, h.Provider, h.Events))
+	if errors.Is(err, worker.ErrWorkerStopped) {
+		if err := h.Db.ReleaseRequest(request.Id, h.LeaseOwner); err != nil {
+			// The lease expires on its own.
+			fmt.Println("Failed to release update request:", err)
+		}
+		return nil
+	}
+	return err
+}
+
+// rejectRequest marks a stored request the worker did not accept as rejected
+// and returns the error to report for it.
+func (h *Handler) rejectRequest(requestId uint64, cause error) error {
+	if err := h.Db.MarkRequestAsRejected(requestId, h.LeaseOwner, cause.Error()); err != nil {
+		fmt.Println("Failed to mark request as rejected:", err)
+	}
+	return fmt.Errorf("update request %d rejected: %w", requestId, cause)
+}
+
+func setRetryAfter(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(constants.QueueFullRetryAfter.Seconds())))
+}
+
+// ValidateCallbackUrl rejects callback URLs that are not absolute http or https
+// URLs of public hosts, so callbacks cannot reach the network of the server.
+func (h *Handler) ValidateCallbackUrl(ctx context.Context, callbackUrl string) error {
+	if !h.CallbacksEnabled {
+		return fmt.Errorf("callback_url is not supported by this server")
+	}
+	var resolver webhooks.Resolver = net.DefaultResolver
+	if h.Resolver != nil {
+		resolver = h.Resolver
+	}
+	return webhooks.CheckCallbackUrl(ctx, resolver, callbackUrl)
+}
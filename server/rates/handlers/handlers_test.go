@@ -2,51 +2,122 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/artem98/ExchangeRateService/server/rates/db"
+	"github.com/artem98/ExchangeRateService/server/rates/external"
 	"github.com/artem98/ExchangeRateService/server/rates/worker"
 	"github.com/go-chi/chi/v5"
+	"github.com/shopspring/decimal"
 )
 
 type mockDb struct {
-	getByPair              func(cur1, cur2 string) (float64, time.Time, error)
-	getByRequestId         func(id uint64) (float64, time.Time, error)
-	placeRequest           func(cur1, cur2 string) (uint64, error)
-	markRequestAsProcessed func(requestId uint64) error
-	markRequestAsFailed    func(requestId uint64) error
-	updateRate             func(currency1, currency2 string, rate float64) error
+	getByPair              func(cur1, cur2 string) (db.RateRecord, error)
+	getRates               func(base string, pairs []db.CurrencyPair) ([]db.PairRateRecord, error)
+	getUpdateRequest       func(id uint64) (db.UpdateRequestRecord, error)
+	getUpdateRequests      func(ids []uint64) ([]db.UpdateRequestRecord, error)
+	placeRequest           func(cur1, cur2, callbackUrl string) (uint64, error)
+	placeRequests          func(pairs []db.CurrencyPair) ([]uint64, error)
+	markRequestAsProcessed func(requestId uint64, produced db.RateRecord) error
+	markRequestAsFailed    func(requestId uint64, reason string) error
+	markRequestAsRejected  func(requestId uint64, reason string) error
+	claimRequests          func(leaseOwner string, limit int) ([]db.UpdateRequestRecord, error)
+	renewLease             func(requestId uint64, leaseOwner string) (bool, error)
+	releaseRequest         func(requestId uint64, leaseOwner string) error
+	retryRequest           func(requestId uint64, leaseOwner, reason string, delay time.Duration) error
+	updateRate             func(currency1, currency2 string, quote external.Quote) (db.RateRecord, error)
+	getRateHistory         func(currency1, currency2 string, from, to time.Time, limit, offset int) ([]db.RateRecord, error)
+	getRateAsOf            func(currency1, currency2 string, asOf time.Time) (db.RateRecord, error)
+	getRateCandles         func(currency1, currency2 string, interval time.Duration, from, to time.Time) ([]db.Candle, error)
+	recordWebhookAttempt   func(attempt db.WebhookAttempt) error
 }
 
-func (m *mockDb) GetRateByPair(currency1, currency2 string) (float64, time.Time, error) {
+func (m *mockDb) GetRateByPair(currency1, currency2 string) (db.RateRecord, error) {
 	return m.getByPair(currency1, currency2)
 }
-func (m *mockDb) GetRateByRequestId(id uint64) (float64, time.Time, error) {
-	return m.getByRequestId(id)
+func (m *mockDb) GetRates(base string, pairs []db.CurrencyPair) ([]db.PairRateRecord, error) {
+	return m.getRates(base, pairs)
 }
-func (m *mockDb) PlaceRequest(currency1, currency2 string) (uint64, error) {
-	return m.placeRequest(currency1, currency2)
+func (m *mockDb) GetUpdateRequest(id uint64) (db.UpdateRequestRecord, error) {
+	return m.getUpdateRequest(id)
 }
-func (m *mockDb) MarkRequestAsProcessed(requestId uint64) error {
-	return m.markRequestAsProcessed(requestId)
+func (m *mockDb) GetUpdateRequests(ids []uint64) ([]db.UpdateRequestRecord, error) {
+	return m.getUpdateRequests(ids)
 }
-func (m *mockDb) MarkRequestAsFailed(requestId uint64) error {
-	return m.markRequestAsFailed(requestId)
+func (m *mockDb) PlaceRequest(currency1, currency2, callbackUrl, leaseOwner string) (uint64, error) {
+	return m.placeRequest(currency1, currency2, callbackUrl)
 }
-func (m *mockDb) UpdateRate(currency1, currency2 string, rate float64) error {
-	return m.updateRate(currency1, currency2, rate)
+func (m *mockDb) PlaceRequests(pairs []db.CurrencyPair, leaseOwner string) ([]uint64, error) {
+	return m.placeRequests(pairs)
+}
+func (m *mockDb) ClaimRequests(leaseOwner string, limit int) ([]db.UpdateRequestRecord, error) {
+	return m.claimRequests(leaseOwner, limit)
+}
+func (m *mockDb) RenewLease(requestId uint64, leaseOwner string) (bool, error) {
+	return m.renewLease(requestId, leaseOwner)
+}
+func (m *mockDb) ReleaseRequest(requestId uint64, leaseOwner string) error {
+	return m.releaseRequest(requestId, leaseOwner)
+}
+func (m *mockDb) RetryRequest(requestId uint64, leaseOwner, reason string, delay time.Duration) error {
+	return m.retryRequest(requestId, leaseOwner, reason, delay)
+}
+func (m *mockDb) MarkRequestAsProcessed(requestId uint64, leaseOwner string, produced db.RateRecord) error {
+	return m.markRequestAsProcessed(requestId, produced)
+}
+func (m *mockDb) MarkRequestAsFailed(requestId uint64, leaseOwner, reason string) error {
+	return m.markRequestAsFailed(requestId, reason)
+}
+func (m *mockDb) MarkRequestAsRejected(requestId uint64, leaseOwner, reason string) error {
+	return m.markRequestAsRejected(requestId, reason)
+}
+func (m *mockDb) UpdateRate(currency1, currency2 string, quote external.Quote) (db.RateRecord, error) {
+	return m.updateRate(currency1, currency2, quote)
+}
+func (m *mockDb) GetRateHistory(currency1, currency2 string, from, to time.Time, limit, offset int) ([]db.RateRecord, error) {
+	return m.getRateHistory(currency1, currency2, from, to, limit, offset)
+}
+func (m *mockDb) GetRateAsOf(currency1, currency2 string, asOf time.Time) (db.RateRecord, error) {
+	return m.getRateAsOf(currency1, currency2, asOf)
+}
+func (m *mockDb) GetRateCandles(currency1, currency2 string, interval time.Duration, from, to time.Time) ([]db.Candle, error) {
+	return m.getRateCandles(currency1, currency2, interval, from, to)
+}
+func (m *mockDb) ClaimWebhooks(limit int) ([]db.PendingWebhook, error) {
+	return nil, nil
+}
+func (m *mockDb) RetryWebhook(requestId uint64, attempts int, delay time.Duration) error {
+	return nil
+}
+func (m *mockDb) FinishWebhook(requestId uint64, attempts int) error {
+	return nil
+}
+func (m *mockDb) ClaimRefresh(pair db.CurrencyPair, hold time.Duration) (bool, error) {
+	return true, nil
+}
+func (m *mockDb) RecordWebhookAttempt(attempt db.WebhookAttempt) error {
+	return m.recordWebhookAttempt(attempt)
 }
 
 type mockWorker struct {
 	planned []worker.Job
+	err     error
 }
 
-func (m *mockWorker) PlanJob(job worker.Job) {
+func (m *mockWorker) PlanJob(job worker.Job) error {
+	if m.err != nil {
+		return m.err
+	}
 	m.planned = append(m.planned, job)
+	return nil
 }
 
 type mockCache struct {
@@ -62,14 +133,30 @@ func (m *mockCache) Set(currency1, currency2 string, id uint64) {
 	m.set(currency1, currency2, id)
 }
 
+// mockResolver resolves IP literals to themselves and every name to a public address.
+type mockResolver struct{}
+
+func (mockResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IPAddr{{IP: ip}}, nil
+	}
+	return []net.IPAddr{{IP: net.ParseIP("93.184.215.14")}}, nil
+}
+
 func TestHandleGetRateByCode(t *testing.T) {
 	handler := &Handler{
 		Db: &mockDb{
-			getByPair: func(cur1, cur2 string) (float64, time.Time, error) {
+			getByPair: func(cur1, cur2 string) (db.RateRecord, error) {
 				if cur1 == "EUR" && cur2 == "USD" {
-					return 1.23, time.Now(), nil
+					return db.RateRecord{
+						Rate:       decimal.RequireFromString("1.23"),
+						UpdateTime: time.Now(),
+						Provider:   "consensus",
+						Sources:    []string{"frankfurter", "erapi"},
+						Spread:     decimal.RequireFromString("0.002"),
+					}, nil
 				}
-				return 0, time.Time{}, errors.New("not found")
+				return db.RateRecord{}, errors.New("not found")
 			},
 		},
 		Worker: &mockWorker{},
@@ -92,16 +179,22 @@ func TestHandleGetRateByCode(t *testing.T) {
 	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
 		t.Errorf("failed to decode response: %v", err)
 	}
-	if resp.Rate != 1.23 {
-		t.Errorf("expected rate 1.23, got %f", resp.Rate)
+	if resp.Rate != "1.23" {
+		t.Errorf("expected rate 1.23, got %s", resp.Rate)
+	}
+	if resp.Provider != "consensus" {
+		t.Errorf("expected provider consensus, got %s", resp.Provider)
+	}
+	if len(resp.Sources) != 2 || resp.Spread != "0.002" {
+		t.Errorf("expected 2 sources with spread 0.002, got %v %s", resp.Sources, resp.Spread)
 	}
 }
 
 func TestHandleGetRateByCodeDBError(t *testing.T) {
 	handler := &Handler{
 		Db: &mockDb{
-			getByPair: func(cur1, cur2 string) (float64, time.Time, error) {
-				return 0, time.Time{}, errors.New("not found")
+			getByPair: func(cur1, cur2 string) (db.RateRecord, error) {
+				return db.RateRecord{}, errors.New("not found")
 			},
 		},
 		Worker: &mockWorker{},
@@ -121,6 +214,95 @@ func TestHandleGetRateByCodeDBError(t *testing.T) {
 	}
 }
 
+func TestHandleGetRateByCodeKeepsExactDigits(t *testing.T) {
+	handler := &Handler{
+		Db: &mockDb{
+			getByPair: func(cur1, cur2 string) (db.RateRecord, error) {
+				return db.RateRecord{Rate: decimal.RequireFromString("1.0850"), UpdateTime: time.Now()}, nil
+			},
+		},
+		Worker: &mockWorker{},
+		Cache:  &mockCache{},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/?currency_pair=EUR/USD", nil)
+	w := httptest.NewRecorder()
+
+	handler.handleGetRateByCode(w, req)
+
+	if !strings.Contains(w.Body.String(), `"rate":1.0850,`) {
+		t.Errorf("expected rate to keep its digits, got %s", w.Body.String())
+	}
+}
+
+func TestHandleGetRateByCodeDerived(t *testing.T) {
+	older := time.Now().Add(-time.Hour)
+	handler := &Handler{
+		Db: &mockDb{
+			getByPair: func(cur1, cur2 string) (db.RateRecord, error) {
+				switch cur1 + "/" + cur2 {
+				case "EUR/USD":
+					return db.RateRecord{Rate: decimal.RequireFromString("1.1"), UpdateTime: time.Now()}, nil
+				case "MXN/USD":
+					return db.RateRecord{Rate: decimal.RequireFromString("0.05"), UpdateTime: older}, nil
+				}
+				return db.RateRecord{}, db.ErrNoSuchPair
+			},
+		},
+		Worker: &mockWorker{},
+		Cache:  &mockCache{},
+		Pivots: []string{"USD"},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/?currency_pair=EUR/MXN", nil)
+	w := httptest.NewRecorder()
+
+	handler.handleGetRateByCode(w, req)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.StatusCode)
+	}
+
+	var resp RateResponse
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.DerivedVia) != 2 || resp.DerivedVia[0].Pair != "EUR/USD" || resp.DerivedVia[1].Pair != "MXN/USD" {
+		t.Errorf("expected legs EUR/USD and MXN/USD, got %+v", resp.DerivedVia)
+	}
+	if !resp.DerivedVia[1].Inverse {
+		t.Errorf("expected MXN/USD leg to be inverse")
+	}
+	if !resp.Timestamp.Equal(older) {
+		t.Errorf("expected oldest leg time %v, got %v", older, resp.Timestamp)
+	}
+}
+
+func TestHandleGetRateByCodeNoSuchPair(t *testing.T) {
+	handler := &Handler{
+		Db: &mockDb{
+			getByPair: func(cur1, cur2 string) (db.RateRecord, error) {
+				return db.RateRecord{}, db.ErrNoSuchPair
+			},
+		},
+		Worker: &mockWorker{},
+		Cache:  &mockCache{},
+		Pivots: []string{"USD", "EUR"},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/?currency_pair=GBP/MXN", nil)
+	w := httptest.NewRecorder()
+
+	handler.handleGetRateByCode(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
+
 func TestHandleGetRateByCodeIncorrectCode(t *testing.T) {
 	handler := &Handler{
 		Db:     &mockDb{},
@@ -161,14 +343,121 @@ func TestHandleGetRateByCodeNoParam(t *testing.T) {
 	}
 }
 
+func TestHandleGetRateByCodeAsOf(t *testing.T) {
+	observed := time.Date(2025, 3, 14, 9, 30, 0, 0, time.UTC)
+	handler := &Handler{
+		Db: &mockDb{
+			getRateAsOf: func(cur1, cur2 string, asOf time.Time) (db.RateRecord, error) {
+				if cur1 == "EUR" && cur2 == "USD" && asOf.Equal(observed.Add(30*time.Minute)) {
+					return db.RateRecord{Rate: decimal.RequireFromString("1.09"), UpdateTime: observed}, nil
+				}
+				return db.RateRecord{}, db.ErrNoRateAtTime
+			},
+		},
+		Worker: &mockWorker{},
+		Cache:  &mockCache{},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/?currency_pair=EUR/USD&as_of=2025-03-14T10:00:00Z", nil)
+	w := httptest.NewRecorder()
+
+	handler.handleGetRateByCode(w, req)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.StatusCode)
+	}
+
+	var resp RateResponse
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Rate != "1.09" || !resp.Timestamp.Equal(observed) {
+		t.Errorf("expected 1.09 observed at %v, got %s at %v", observed, resp.Rate, resp.Timestamp)
+	}
+	if resp.AsOf == nil || !resp.AsOf.Equal(observed.Add(30*time.Minute)) {
+		t.Errorf("expected as_of to be echoed, got %v", resp.AsOf)
+	}
+}
+
+func TestHandleGetRateByCodeAsOfWithOffset(t *testing.T) {
+	var queried time.Time
+	handler := &Handler{
+		Db: &mockDb{
+			getRateAsOf: func(cur1, cur2 string, asOf time.Time) (db.RateRecord, error) {
+				queried = asOf
+				return db.RateRecord{Rate: decimal.RequireFromString("1.09"), UpdateTime: asOf}, nil
+			},
+		},
+		Worker: &mockWorker{},
+		Cache:  &mockCache{},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/?currency_pair=EUR/USD&as_of=2025-01-01T00:00:00%2B03:00", nil)
+	w := httptest.NewRecorder()
+	handler.handleGetRateByCode(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	want := time.Date(2024, 12, 31, 21, 0, 0, 0, time.UTC)
+	if queried != want {
+		t.Errorf("expected the rate as of %v, queried %v", want, queried)
+	}
+}
+
+func TestHandleGetRateByCodeAsOfNoData(t *testing.T) {
+	handler := &Handler{
+		Db: &mockDb{
+			getRateAsOf: func(cur1, cur2 string, asOf time.Time) (db.RateRecord, error) {
+				return db.RateRecord{}, db.ErrNoRateAtTime
+			},
+		},
+		Worker: &mockWorker{},
+		Cache:  &mockCache{},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/?currency_pair=EUR/USD&as_of=1999-01-01", nil)
+	w := httptest.NewRecorder()
+
+	handler.handleGetRateByCode(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestHandleGetRateByCodeAsOfInvalid(t *testing.T) {
+	handler := &Handler{
+		Db:     &mockDb{},
+		Worker: &mockWorker{},
+		Cache:  &mockCache{},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/?currency_pair=EUR/USD&as_of=yesterday", nil)
+	w := httptest.NewRecorder()
+
+	handler.handleGetRateByCode(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
+
 func TestHandleGetRateByUpdateId(t *testing.T) {
 	handler := &Handler{
 		Db: &mockDb{
-			getByRequestId: func(id uint64) (float64, time.Time, error) {
+			getUpdateRequest: func(id uint64) (db.UpdateRequestRecord, error) {
 				if id == 42 {
-					return 1.5, time.Now(), nil
+					return db.UpdateRequestRecord{
+						Id: 42, Currency1: "EUR", Currency2: "USD", Status: db.RequestOk,
+						CreatedAt: time.Now(), CompletedAt: time.Now(),
+						Rate: db.RateRecord{Rate: decimal.RequireFromString("1.5"), UpdateTime: time.Now()},
+					}, nil
 				}
-				return 0, time.Time{}, errors.New("not found")
+				return db.UpdateRequestRecord{}, db.ErrNoSuchRequest
 			},
 		},
 		Worker: &mockWorker{},
@@ -189,16 +478,123 @@ func TestHandleGetRateByUpdateId(t *testing.T) {
 	if res.StatusCode != http.StatusOK {
 		t.Errorf("expected 200, got %d", res.StatusCode)
 	}
+
+	var resp UpdateStatusResponse
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Status != db.RequestOk || resp.Pair != "EUR/USD" || resp.CompletedAt == nil || resp.Error != nil {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if resp.RateResponse == nil || resp.Rate != "1.5" {
+		t.Errorf("expected produced rate 1.5, got %+v", resp.RateResponse)
+	}
+}
+
+func TestHandleGetRateByUpdateIdPending(t *testing.T) {
+	handler := &Handler{
+		Db: &mockDb{
+			getUpdateRequest: func(id uint64) (db.UpdateRequestRecord, error) {
+				return db.UpdateRequestRecord{
+					Id: id, Currency1: "EUR", Currency2: "USD", Status: db.RequestSubmitted, CreatedAt: time.Now(),
+				}, nil
+			},
+		},
+		Worker: &mockWorker{},
+		Cache:  &mockCache{},
+	}
+
+	r := chi.NewRouter()
+	r.Get("/update_requests/{id}", handler.handleGetRateByUpdateId)
+
+	req := httptest.NewRequest(http.MethodGet, "/update_requests/42", nil)
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Errorf("expected 202, got %d", w.Code)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, `"status":"submitted"`) || strings.Contains(body, `"rate"`) || strings.Contains(body, `"completed_at"`) {
+		t.Errorf("unexpected response body: %s", body)
+	}
+}
+
+func TestHandleGetRateByUpdateIdFailed(t *testing.T) {
+	handler := &Handler{
+		Db: &mockDb{
+			getUpdateRequest: func(id uint64) (db.UpdateRequestRecord, error) {
+				return db.UpdateRequestRecord{
+					Id: id, Currency1: "EUR", Currency2: "USD", Status: db.RequestFailed,
+					CreatedAt: time.Now(), CompletedAt: time.Now(), FailureReason: "all rate providers failed",
+				}, nil
+			},
+		},
+		Worker: &mockWorker{},
+		Cache:  &mockCache{},
+	}
+
+	r := chi.NewRouter()
+	r.Get("/update_requests/{id}", handler.handleGetRateByUpdateId)
+
+	req := httptest.NewRequest(http.MethodGet, "/update_requests/42", nil)
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", w.Code)
+	}
+
+	var resp UpdateStatusResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Status != db.RequestFailed || resp.Error == nil || resp.Error.Reason != "all rate providers failed" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if resp.RateResponse != nil {
+		t.Errorf("expected no rate for failed request, got %+v", resp.RateResponse)
+	}
+}
+
+func TestHandleGetRateByUpdateIdUnknown(t *testing.T) {
+	handler := &Handler{
+		Db: &mockDb{
+			getUpdateRequest: func(id uint64) (db.UpdateRequestRecord, error) {
+				return db.UpdateRequestRecord{}, db.ErrNoSuchRequest
+			},
+		},
+		Worker: &mockWorker{},
+		Cache:  &mockCache{},
+	}
+
+	r := chi.NewRouter()
+	r.Get("/update_requests/{id}", handler.handleGetRateByUpdateId)
+
+	req := httptest.NewRequest(http.MethodGet, "/update_requests/7", nil)
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
 }
 
 func TestHandleGetRateByUpdateIdNotUint64(t *testing.T) {
 	handler := &Handler{
 		Db: &mockDb{
-			getByRequestId: func(id uint64) (float64, time.Time, error) {
+			getUpdateRequest: func(id uint64) (db.UpdateRequestRecord, error) {
 				if id == 42 {
-					return 1.5, time.Now(), nil
+					return db.UpdateRequestRecord{
+						Id: 42, Currency1: "EUR", Currency2: "USD", Status: db.RequestOk,
+						CreatedAt: time.Now(), CompletedAt: time.Now(),
+						Rate: db.RateRecord{Rate: decimal.RequireFromString("1.5"), UpdateTime: time.Now()},
+					}, nil
 				}
-				return 0, time.Time{}, errors.New("not found")
+				return db.UpdateRequestRecord{}, db.ErrNoSuchRequest
 			},
 		},
 		Worker: &mockWorker{},
@@ -224,8 +620,8 @@ func TestHandleGetRateByUpdateIdNotUint64(t *testing.T) {
 func TestHandleGetRateByUpdateIdDBError(t *testing.T) {
 	handler := &Handler{
 		Db: &mockDb{
-			getByRequestId: func(id uint64) (float64, time.Time, error) {
-				return 0, time.Time{}, errors.New("not found")
+			getUpdateRequest: func(id uint64) (db.UpdateRequestRecord, error) {
+				return db.UpdateRequestRecord{}, errors.New("connection refused")
 			},
 		},
 		Worker: &mockWorker{},
@@ -251,11 +647,15 @@ func TestHandleGetRateByUpdateIdDBError(t *testing.T) {
 func TestHandleGetRateByUpdateIdNoId(t *testing.T) {
 	handler := &Handler{
 		Db: &mockDb{
-			getByRequestId: func(id uint64) (float64, time.Time, error) {
+			getUpdateRequest: func(id uint64) (db.UpdateRequestRecord, error) {
 				if id == 42 {
-					return 1.5, time.Now(), nil
+					return db.UpdateRequestRecord{
+						Id: 42, Currency1: "EUR", Currency2: "USD", Status: db.RequestOk,
+						CreatedAt: time.Now(), CompletedAt: time.Now(),
+						Rate: db.RateRecord{Rate: decimal.RequireFromString("1.5"), UpdateTime: time.Now()},
+					}, nil
 				}
-				return 0, time.Time{}, errors.New("not found")
+				return db.UpdateRequestRecord{}, db.ErrNoSuchRequest
 			},
 		},
 		Worker: &mockWorker{},
@@ -282,7 +682,7 @@ func TestHandlePostRateUpdateRequestFoundInCache(t *testing.T) {
 	mockW := &mockWorker{}
 	handler := &Handler{
 		Db: &mockDb{
-			placeRequest: func(cur1, cur2 string) (uint64, error) {
+			placeRequest: func(cur1, cur2, callbackUrl string) (uint64, error) {
 				if cur1 == "EUR" && cur2 == "USD" {
 					return 777, nil
 				}
@@ -328,7 +728,7 @@ func TestHandlePostRateUpdateRequest(t *testing.T) {
 	mockW := &mockWorker{}
 	handler := &Handler{
 		Db: &mockDb{
-			placeRequest: func(cur1, cur2 string) (uint64, error) {
+			placeRequest: func(cur1, cur2, callbackUrl string) (uint64, error) {
 				if cur1 == "EUR" && cur2 == "USD" {
 					return 777, nil
 				}
@@ -370,11 +770,106 @@ func TestHandlePostRateUpdateRequest(t *testing.T) {
 	}
 }
 
+func TestHandlePostRateUpdateRequestQueueFull(t *testing.T) {
+	var rejected uint64
+	var cached bool
+	handler := &Handler{
+		Db: &mockDb{
+			placeRequest: func(cur1, cur2, callbackUrl string) (uint64, error) {
+				return 777, nil
+			},
+			markRequestAsRejected: func(requestId uint64, reason string) error {
+				rejected = requestId
+				return nil
+			},
+		},
+		Worker: &mockWorker{err: worker.ErrQueueFull},
+		Cache: &mockCache{
+			get: func(currency1, currency2 string) (uint64, bool) {
+				return 0, false
+			},
+			set: func(currency1, currency2 string, id uint64) {
+				cached = true
+			},
+		},
+	}
+
+	body := []byte(`{"pair":"EUR/USD"}`)
+	req := httptest.NewRequest(http.MethodPost, "/update_requests", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	handler.handlePostRateUpdateRequest(w, req)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected 503, got %d", res.StatusCode)
+	}
+	if res.Header.Get("Retry-After") != "5" {
+		t.Errorf("expected Retry-After 5, got %q", res.Header.Get("Retry-After"))
+	}
+	if rejected != 777 {
+		t.Errorf("expected request 777 to be marked as rejected, got %d", rejected)
+	}
+	if cached {
+		t.Errorf("expected rejected request not to be cached")
+	}
+}
+
+func TestHandlePostRateUpdateRequestWorkerStopped(t *testing.T) {
+	var released uint64
+	handler := &Handler{
+		Db: &mockDb{
+			placeRequest: func(cur1, cur2, callbackUrl string) (uint64, error) {
+				return 777, nil
+			},
+			releaseRequest: func(requestId uint64, leaseOwner string) error {
+				released = requestId
+				return nil
+			},
+			markRequestAsRejected: func(requestId uint64, reason string) error {
+				t.Errorf("expected request %d not to be rejected", requestId)
+				return nil
+			},
+		},
+		Worker: &mockWorker{err: worker.ErrWorkerStopped},
+		Cache: &mockCache{
+			get: func(currency1, currency2 string) (uint64, bool) {
+				return 0, false
+			},
+			set: func(currency1, currency2 string, id uint64) {},
+		},
+	}
+
+	body := []byte(`{"pair":"EUR/USD"}`)
+	req := httptest.NewRequest(http.MethodPost, "/update_requests", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	handler.handlePostRateUpdateRequest(w, req)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("expected 200, got %d", res.StatusCode)
+	}
+	var resp UpdateResponse
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		t.Errorf("failed to decode response: %v", err)
+	}
+	if resp.UpdateID != 777 || released != 777 {
+		t.Errorf("expected request 777 to be accepted and left to the queue, got %d released %d", resp.UpdateID, released)
+	}
+}
+
 func TestHandlePostRateUpdateRequestNotJson(t *testing.T) {
 	mockW := &mockWorker{}
 	handler := &Handler{
 		Db: &mockDb{
-			placeRequest: func(cur1, cur2 string) (uint64, error) {
+			placeRequest: func(cur1, cur2, callbackUrl string) (uint64, error) {
 				if cur1 == "EUR" && cur2 == "USD" {
 					return 777, nil
 				}
@@ -403,7 +898,7 @@ func TestHandlePostRateUpdateRequestWrongJson(t *testing.T) {
 	mockW := &mockWorker{}
 	handler := &Handler{
 		Db: &mockDb{
-			placeRequest: func(cur1, cur2 string) (uint64, error) {
+			placeRequest: func(cur1, cur2, callbackUrl string) (uint64, error) {
 				if cur1 == "EUR" && cur2 == "USD" {
 					return 777, nil
 				}
@@ -433,7 +928,7 @@ func TestHandlePostRateUpdateRequestWrongJsonValues(t *testing.T) {
 	mockW := &mockWorker{}
 	handler := &Handler{
 		Db: &mockDb{
-			placeRequest: func(cur1, cur2 string) (uint64, error) {
+			placeRequest: func(cur1, cur2, callbackUrl string) (uint64, error) {
 				if cur1 == "EUR" && cur2 == "USD" {
 					return 777, nil
 				}
@@ -463,7 +958,7 @@ func TestHandlePostRateUpdateRequestWrongPair(t *testing.T) {
 	mockW := &mockWorker{}
 	handler := &Handler{
 		Db: &mockDb{
-			placeRequest: func(cur1, cur2 string) (uint64, error) {
+			placeRequest: func(cur1, cur2, callbackUrl string) (uint64, error) {
 				if cur1 == "EUR" && cur2 == "USD" {
 					return 777, nil
 				}
@@ -493,7 +988,7 @@ func TestHandlePostRateUpdateRequestDBError(t *testing.T) {
 	mockW := &mockWorker{}
 	handler := &Handler{
 		Db: &mockDb{
-			placeRequest: func(cur1, cur2 string) (uint64, error) {
+			placeRequest: func(cur1, cur2, callbackUrl string) (uint64, error) {
 				return 0, errors.New("invalid")
 			},
 		},
@@ -520,3 +1015,71 @@ func TestHandlePostRateUpdateRequestDBError(t *testing.T) {
 		t.Errorf("expected 500, got %d", res.StatusCode)
 	}
 }
+
+func TestHandlePostRateUpdateRequestWithCallback(t *testing.T) {
+	mockW := &mockWorker{}
+	var placedCallback string
+	handler := &Handler{
+		Db: &mockDb{
+			placeRequest: func(cur1, cur2, callbackUrl string) (uint64, error) {
+				placedCallback = callbackUrl
+				return 43, nil
+			},
+		},
+		Worker: mockW,
+		Cache: &mockCache{
+			get: func(currency1, currency2 string) (uint64, bool) { return 42, true },
+			set: func(currency1, currency2 string, id uint64) {},
+		},
+		CallbacksEnabled: true,
+		Resolver:         mockResolver{},
+	}
+
+	body := []byte(`{"pair":"EUR/USD","callback_url":"https://example.com/hook"}`)
+	req := httptest.NewRequest(http.MethodPost, "/update_requests", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handler.handlePostRateUpdateRequest(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"update_request_id":43`) {
+		t.Errorf("expected a new request despite the cached one, got %s", w.Body.String())
+	}
+	if placedCallback != "https://example.com/hook" || len(mockW.planned) != 1 {
+		t.Errorf("expected request with callback to be placed and planned, got %q", placedCallback)
+	}
+}
+
+func TestHandlePostRateUpdateRequestBadCallback(t *testing.T) {
+	for _, tc := range []struct {
+		enabled bool
+		url     string
+	}{
+		{enabled: false, url: "https://example.com/hook"},
+		{enabled: true, url: "example.com/hook"},
+		{enabled: true, url: "ftp://example.com/hook"},
+		{enabled: true, url: "http://127.0.0.1:8081/admin/reload"},
+		{enabled: true, url: "http://169.254.169.254/latest/meta-data"},
+		{enabled: true, url: "http://[::1]/hook"},
+	} {
+		handler := &Handler{
+			Db:               &mockDb{},
+			Worker:           &mockWorker{},
+			Cache:            &mockCache{},
+			CallbacksEnabled: tc.enabled,
+			Resolver:         mockResolver{},
+		}
+
+		body := []byte(`{"pair":"EUR/USD","callback_url":"` + tc.url + `"}`)
+		req := httptest.NewRequest(http.MethodPost, "/update_requests", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler.handlePostRateUpdateRequest(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%+v: expected 400, got %d", tc, w.Code)
+		}
+	}
+}
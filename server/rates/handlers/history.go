@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/artem98/ExchangeRateService/server/constants"
+	"github.com/artem98/ExchangeRateService/server/rates/utils"
+)
+
+type HistoryResponse struct {
+	CurrencyPair string         `json:"currency_pair"`
+	Rates        []RateResponse `json:"rates"`
+	NextOffset   *int           `json:"next_offset,omitempty"`
+}
+
+func (h *Handler) handleGetRateHistory(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	currencyPair := query.Get("currency_pair")
+	if currencyPair == "" {
+		http.Error(w, "currency_pair query parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	currency1, currency2, err := utils.ParseCurrencyPair(currencyPair)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	from, err := parseTimeParam(query.Get("from"), time.Unix(0, 0))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	to, err := parseTimeParam(query.Get("to"), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !from.Before(to) {
+		http.Error(w, "from must be before to", http.StatusBadRequest)
+		return
+	}
+
+	limit, offset, err := parsePage(query.Get("limit"), query.Get("offset"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Times are stored without a time zone, in UTC. One extra row tells
+	// whether there is a next page.
+	records, err := h.Db.GetRateHistory(currency1, currency2, from.UTC(), to.UTC(), limit+1, offset)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := HistoryResponse{CurrencyPair: currency1 + "/" + currency2, Rates: []RateResponse{}}
+	if len(records) > limit {
+		records = records[:limit]
+		nextOffset := offset + limit
+		response.NextOffset = &nextOffset
+	}
+	for _, record := range records {
+		response.Rates = append(response.Rates, makeRateResponse(record))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		http.Error(w, "internal json problem", http.StatusInternalServerError)
+	}
+}
+
+func parseTimeParam(value string, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	return utils.ParseTimestamp(value)
+}
+
+func parsePage(limitParam, offsetParam string) (limit, offset int, err error) {
+	limit = constants.DefaultPageLimit
+	if limitParam != "" {
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit <= 0 || limit > constants.MaxPageLimit {
+			return 0, 0, fmt.Errorf("limit must be an integer between 1 and %d", constants.MaxPageLimit)
+		}
+	}
+
+	if offsetParam != "" {
+		offset, err = strconv.Atoi(offsetParam)
+		if err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("offset must be a non-negative integer")
+		}
+	}
+
+	return limit, offset, nil
+}
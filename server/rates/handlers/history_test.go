@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/artem98/ExchangeRateService/server/rates/db"
+	"github.com/shopspring/decimal"
+)
+
+func TestHandleGetRateHistory(t *testing.T) {
+	var gotFrom, gotTo time.Time
+	var gotLimit, gotOffset int
+	handler := &Handler{
+		Db: &mockDb{
+			getRateHistory: func(cur1, cur2 string, from, to time.Time, limit, offset int) ([]db.RateRecord, error) {
+				gotFrom, gotTo, gotLimit, gotOffset = from, to, limit, offset
+				return []db.RateRecord{
+					{Rate: decimal.RequireFromString("1.1"), UpdateTime: from.Add(time.Hour)},
+					{Rate: decimal.RequireFromString("1.2"), UpdateTime: from.Add(2 * time.Hour)},
+					{Rate: decimal.RequireFromString("1.3"), UpdateTime: from.Add(3 * time.Hour)},
+				}, nil
+			},
+		},
+		Worker: &mockWorker{},
+		Cache:  &mockCache{},
+	}
+
+	req := httptest.NewRequest(http.MethodGet,
+		"/history?currency_pair=EUR/USD&from=2025-01-01&to=2025-01-02T00:00:00Z&limit=2&offset=4", nil)
+	w := httptest.NewRecorder()
+
+	handler.handleGetRateHistory(w, req)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.StatusCode)
+	}
+	if !gotFrom.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) || !gotTo.Equal(time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected time range %v - %v", gotFrom, gotTo)
+	}
+	if gotLimit != 3 || gotOffset != 4 {
+		t.Errorf("expected limit 3 offset 4, got %d %d", gotLimit, gotOffset)
+	}
+
+	var resp HistoryResponse
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Rates) != 2 {
+		t.Errorf("expected 2 rates, got %d", len(resp.Rates))
+	}
+	if resp.NextOffset == nil || *resp.NextOffset != 6 {
+		t.Errorf("expected next offset 6, got %v", resp.NextOffset)
+	}
+}
+
+func TestHandleGetRateHistoryWithOffset(t *testing.T) {
+	var gotFrom, gotTo time.Time
+	handler := &Handler{
+		Db: &mockDb{
+			getRateHistory: func(cur1, cur2 string, from, to time.Time, limit, offset int) ([]db.RateRecord, error) {
+				gotFrom, gotTo = from, to
+				return nil, nil
+			},
+		},
+		Worker: &mockWorker{},
+		Cache:  &mockCache{},
+	}
+
+	req := httptest.NewRequest(http.MethodGet,
+		"/history?currency_pair=EUR/USD&from=2025-01-01T00:00:00%2B03:00&to=2025-01-01T12:00:00-05:00", nil)
+	w := httptest.NewRecorder()
+	handler.handleGetRateHistory(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	wantFrom := time.Date(2024, 12, 31, 21, 0, 0, 0, time.UTC)
+	wantTo := time.Date(2025, 1, 1, 17, 0, 0, 0, time.UTC)
+	if gotFrom != wantFrom || gotTo != wantTo {
+		t.Errorf("expected window %v - %v, got %v - %v", wantFrom, wantTo, gotFrom, gotTo)
+	}
+}
+
+func TestHandleGetRateHistoryLastPage(t *testing.T) {
+	handler := &Handler{
+		Db: &mockDb{
+			getRateHistory: func(cur1, cur2 string, from, to time.Time, limit, offset int) ([]db.RateRecord, error) {
+				return []db.RateRecord{{Rate: decimal.RequireFromString("1.1"), UpdateTime: time.Now()}}, nil
+			},
+		},
+		Worker: &mockWorker{},
+		Cache:  &mockCache{},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/history?currency_pair=EUR/USD", nil)
+	w := httptest.NewRecorder()
+
+	handler.handleGetRateHistory(w, req)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	var resp HistoryResponse
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Rates) != 1 || resp.NextOffset != nil {
+		t.Errorf("expected single rate without next offset, got %d %v", len(resp.Rates), resp.NextOffset)
+	}
+}
+
+func TestHandleGetRateHistoryBadParams(t *testing.T) {
+	handler := &Handler{
+		Db:     &mockDb{},
+		Worker: &mockWorker{},
+		Cache:  &mockCache{},
+	}
+
+	urls := []string{
+		"/history",
+		"/history?currency_pair=EURUSD",
+		"/history?currency_pair=EUR/USD&from=yesterday",
+		"/history?currency_pair=EUR/USD&from=2025-01-02&to=2025-01-01",
+		"/history?currency_pair=EUR/USD&limit=0",
+		"/history?currency_pair=EUR/USD&offset=-1",
+	}
+	for _, url := range urls {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		w := httptest.NewRecorder()
+
+		handler.handleGetRateHistory(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", url, w.Code)
+		}
+	}
+}
+
+func TestHandleGetRateHistoryDBError(t *testing.T) {
+	handler := &Handler{
+		Db: &mockDb{
+			getRateHistory: func(cur1, cur2 string, from, to time.Time, limit, offset int) ([]db.RateRecord, error) {
+				return nil, errors.New("db down")
+			},
+		},
+		Worker: &mockWorker{},
+		Cache:  &mockCache{},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/history?currency_pair=EUR/USD", nil)
+	w := httptest.NewRecorder()
+
+	handler.handleGetRateHistory(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", w.Code)
+	}
+}
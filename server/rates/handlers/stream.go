@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/artem98/ExchangeRateService/server/constants"
+	"github.com/artem98/ExchangeRateService/server/rates/db"
+	"github.com/artem98/ExchangeRateService/server/rates/events"
+	"github.com/artem98/ExchangeRateService/server/rates/utils"
+)
+
+// handleRateStream sends a Server-Sent Event for every rate stored for the
+// requested pairs, or for all pairs when none are given. A reconnecting client
+// gets the events it missed after the id in its Last-Event-ID header.
+func (h *Handler) handleRateStream(w http.ResponseWriter, r *http.Request) {
+	var pairs []db.CurrencyPair
+	if pairsParam := r.URL.Query().Get("pairs"); pairsParam != "" {
+		for _, code := range strings.Split(pairsParam, ",") {
+			currency1, currency2, err := utils.ParseCurrencyPair(strings.TrimSpace(code))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			pairs = append(pairs, db.CurrencyPair{Currency1: currency1, Currency2: currency2})
+		}
+	}
+
+	var lastEventId uint64
+	if lastEventParam := r.Header.Get("Last-Event-ID"); lastEventParam != "" {
+		var err error
+		lastEventId, err = strconv.ParseUint(lastEventParam, 10, 64)
+		if err != nil {
+			http.Error(w, "Last-Event-ID must be uint64", http.StatusBadRequest)
+			return
+		}
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	subscription, missed, err := h.Events.Subscribe(pairs, lastEventId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer h.Events.Unsubscribe(subscription)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	for _, event := range missed {
+		if err := writeRateEvent(w, event); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(constants.StreamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-h.ShuttingDown:
+			return
+		case event, ok := <-subscription.C:
+			if !ok {
+				// Dropped for falling behind, the client resumes from its last event.
+				return
+			}
+			if err := writeRateEvent(w, event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+func writeRateEvent(w http.ResponseWriter, event events.RateEvent) error {
+	data, err := json.Marshal(PairRateResponse{
+		CurrencyPair: event.Currency1 + "/" + event.Currency2,
+		RateResponse: makeRateResponse(event.Record),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: rate\ndata: %s\n\n", event.Id, data)
+	return err
+}
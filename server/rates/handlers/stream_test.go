@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/artem98/ExchangeRateService/server/rates/db"
+	"github.com/artem98/ExchangeRateService/server/rates/events"
+	"github.com/shopspring/decimal"
+)
+
+// historyStore keeps the stored rates in memory, numbered like rate_history.
+type historyStore struct {
+	mu      sync.Mutex
+	changes []db.RateChange
+}
+
+func (s *historyStore) LastRateChangeId() (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return uint64(len(s.changes)), nil
+}
+
+func (s *historyStore) GetRateChanges(afterId uint64, limit int) ([]db.RateChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changes := s.changes[min(afterId, uint64(len(s.changes))):]
+	return slices.Clone(changes[:min(limit, len(changes))]), nil
+}
+
+func (s *historyStore) GetLatestRateChanges(pairs []db.CurrencyPair, afterId, upToId uint64, limit int) ([]db.RateChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var changes []db.RateChange
+	for _, change := range s.changes {
+		if change.Id > afterId && change.Id <= upToId && (len(pairs) == 0 || slices.Contains(pairs, change.CurrencyPair)) {
+			changes = append(changes, change)
+		}
+	}
+	return changes[max(len(changes)-limit, 0):], nil
+}
+
+// makeBroker returns a broker and a function storing a rate and delivering it.
+func makeBroker(t *testing.T) (*events.Broker, func(currency1, currency2 string, record db.RateRecord)) {
+	t.Helper()
+	history := &historyStore{}
+	broker, err := events.MakeBroker(history, 10, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publish := func(currency1, currency2 string, record db.RateRecord) {
+		history.mu.Lock()
+		history.changes = append(history.changes, db.RateChange{
+			Id:           uint64(len(history.changes) + 1),
+			CurrencyPair: db.CurrencyPair{Currency1: currency1, Currency2: currency2},
+			RateRecord:   record,
+		})
+		history.mu.Unlock()
+		if err := broker.Poll(); err != nil {
+			t.Error(err)
+		}
+	}
+	return broker, publish
+}
+
+func readEvent(t *testing.T, reader *bufio.Reader) []string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read event: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		if line == "" {
+			return lines
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestHandleRateStream(t *testing.T) {
+	broker, publish := makeBroker(t)
+	publish("EUR", "USD", db.RateRecord{Rate: decimal.RequireFromString("1.07")})
+	publish("EUR", "USD", db.RateRecord{Rate: decimal.RequireFromString("1.08")})
+	handler := &Handler{Events: broker}
+
+	server := httptest.NewServer(http.HandlerFunc(handler.handleRateStream))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"?pairs=EUR/USD,gbp/usd", nil)
+	req.Header.Set("Last-Event-ID", "1")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected response %d %s", res.StatusCode, res.Header.Get("Content-Type"))
+	}
+	reader := bufio.NewReader(res.Body)
+
+	// The event after Last-Event-ID is replayed first.
+	lines := readEvent(t, reader)
+	if len(lines) != 3 || lines[0] != "id: 2" || lines[1] != "event: rate" || !strings.Contains(lines[2], `"rate":1.08`) {
+		t.Errorf("unexpected replayed event %q", lines)
+	}
+
+	publish("MXN", "USD", db.RateRecord{Rate: decimal.RequireFromString("0.05")})
+	publish("GBP", "USD", db.RateRecord{Rate: decimal.RequireFromString("1.27")})
+
+	lines = readEvent(t, reader)
+	if len(lines) != 3 || lines[0] != "id: 4" || !strings.Contains(lines[2], `"currency_pair":"GBP/USD"`) {
+		t.Errorf("unexpected live event %q", lines)
+	}
+}
+
+func TestHandleRateStreamEndsOnShutdown(t *testing.T) {
+	shuttingDown := make(chan struct{})
+	broker, _ := makeBroker(t)
+	handler := &Handler{Events: broker, ShuttingDown: shuttingDown}
+
+	req := httptest.NewRequest(http.MethodGet, "/stream?pairs=EUR/USD", nil)
+	done := make(chan struct{})
+	go func() {
+		handler.handleRateStream(httptest.NewRecorder(), req)
+		close(done)
+	}()
+
+	close(shuttingDown)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected the stream to end when the server shuts down")
+	}
+}
+
+func TestHandleRateStreamBadParams(t *testing.T) {
+	broker, _ := makeBroker(t)
+	handler := &Handler{Events: broker}
+
+	req := httptest.NewRequest(http.MethodGet, "/stream?pairs=EURUSD", nil)
+	w := httptest.NewRecorder()
+	handler.handleRateStream(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for bad pair, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/stream", nil)
+	req.Header.Set("Last-Event-ID", "abc")
+	w = httptest.NewRecorder()
+	handler.handleRateStream(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for bad Last-Event-ID, got %d", w.Code)
+	}
+}
@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/artem98/ExchangeRateService/server/constants"
+	"github.com/artem98/ExchangeRateService/server/rates/db"
+	"github.com/artem98/ExchangeRateService/server/rates/events"
+	"github.com/artem98/ExchangeRateService/server/rates/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+)
+
+// SubscriptionMessage is sent by WebSocket clients to change what they follow.
+type SubscriptionMessage struct {
+	Action           string   `json:"action"`
+	Pairs            []string `json:"pairs"`
+	UpdateRequestIds []uint64 `json:"update_request_ids"`
+}
+
+type SubscriptionsMessage struct {
+	Type             string   `json:"type"`
+	Pairs            []string `json:"pairs"`
+	UpdateRequestIds []uint64 `json:"update_request_ids"`
+}
+
+type RateMessage struct {
+	Type string `json:"type"`
+	PairRateResponse
+}
+
+type UpdateRequestMessage struct {
+	Type string `json:"type"`
+	UpdateStatusResponse
+}
+
+type ErrorMessage struct {
+	Type  string `json:"type"`
+	Error string `json:"error"`
+}
+
+// Without a CheckOrigin the upgrader refuses browsers on pages of other hosts,
+// like the rest of the API which sends no CORS headers.
+var upgrader = websocket.Upgrader{}
+
+func (h *Handler) HandleWebSocket(r chi.Router) {
+	r.Get("/", handlerWithMiddleware(h.handleWebSocket))
+}
+
+type clientMessage struct {
+	message SubscriptionMessage
+	err     error
+}
+
+// wsSession holds what one WebSocket client follows. It is only used from the
+// goroutine serving the connection, which is also the only one writing to it.
+type wsSession struct {
+	h          *Handler
+	conn       *websocket.Conn
+	pairs      map[db.CurrencyPair]bool
+	requestIds map[uint64]bool
+}
+
+func (h *Handler) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already replied with an error.
+		fmt.Println("WebSocket upgrade failed:", err)
+		return
+	}
+	defer conn.Close()
+	// Larger messages fail the read and close the connection.
+	conn.SetReadLimit(constants.WebSocketReadLimit)
+
+	session := &wsSession{
+		h:          h,
+		conn:       conn,
+		pairs:      make(map[db.CurrencyPair]bool),
+		requestIds: make(map[uint64]bool),
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	messages := make(chan clientMessage)
+	go func() {
+		defer close(messages)
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var message clientMessage
+			message.err = json.Unmarshal(data, &message.message)
+			select {
+			case messages <- message:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	// Every rate is received and filtered here, so changing the followed pairs
+	// does not need a new subscription.
+	rates, _, err := h.Events.Subscribe(nil, 0)
+	if err != nil {
+		fmt.Println("WebSocket connection closed:", err)
+		return
+	}
+	requests := h.Events.SubscribeRequests()
+	defer func() {
+		h.Events.Unsubscribe(rates)
+		h.Events.UnsubscribeRequests(requests)
+	}()
+	lastEventId := rates.After
+
+	ping := time.NewTicker(constants.StreamHeartbeatInterval)
+	defer ping.Stop()
+	// Only the requests finished by this server are announced, the ones other
+	// servers finish are found by reading the followed requests back.
+	poll := time.NewTicker(constants.RequestPollInterval)
+	defer poll.Stop()
+
+	for {
+		var err error
+		select {
+		case message, ok := <-messages:
+			if !ok {
+				return
+			}
+			err = session.handleMessage(message)
+		case event, ok := <-rates.C:
+			if !ok {
+				// Dropped for falling behind, pick up from the last seen event.
+				var missed []events.RateEvent
+				if rates, missed, err = h.Events.Subscribe(nil, lastEventId); err != nil {
+					break
+				}
+				for _, event := range missed {
+					lastEventId = event.Id
+					if err = session.sendRate(event); err != nil {
+						break
+					}
+				}
+				break
+			}
+			lastEventId = event.Id
+			err = session.sendRate(event)
+		case event, ok := <-requests.C:
+			if !ok {
+				// Completions may have been missed, read the followed requests back.
+				requests = h.Events.SubscribeRequests()
+				err = session.checkRequests(session.followedRequests())
+				break
+			}
+			if session.requestIds[event.RequestId] {
+				err = session.checkRequests([]uint64{event.RequestId})
+			}
+		case <-poll.C:
+			err = session.checkRequests(session.followedRequests())
+		case <-ping.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(constants.WebSocketWriteTimeout))
+		case <-h.ShuttingDown:
+			message := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server is shutting down")
+			conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(constants.WebSocketWriteTimeout))
+			return
+		}
+		if err != nil {
+			fmt.Println("WebSocket connection closed:", err)
+			return
+		}
+	}
+}
+
+func (s *wsSession) send(message any) error {
+	s.conn.SetWriteDeadline(time.Now().Add(constants.WebSocketWriteTimeout))
+	return s.conn.WriteJSON(message)
+}
+
+func (s *wsSession) sendError(err error) error {
+	return s.send(ErrorMessage{Type: "error", Error: err.Error()})
+}
+
+func (s *wsSession) sendRate(event events.RateEvent) error {
+	if !s.pairs[event.CurrencyPair] {
+		return nil
+	}
+	return s.send(RateMessage{
+		Type: "rate",
+		PairRateResponse: PairRateResponse{
+			CurrencyPair: event.Currency1 + "/" + event.Currency2,
+			RateResponse: makeRateResponse(event.Record),
+		},
+	})
+}
+
+func (s *wsSession) followedRequests() []uint64 {
+	return slices.Sorted(maps.Keys(s.requestIds))
+}
+
+// checkRequests reads the requests back in one query, notifies the client of
+// the finished ones and stops following them. Requests that do not exist are
+// reported and dropped.
+func (s *wsSession) checkRequests(ids []uint64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	records, err := s.h.Db.GetUpdateRequests(ids)
+	if err != nil {
+		return s.sendError(err)
+	}
+
+	checked := make(map[uint64]bool, len(ids))
+	for _, record := range records {
+		checked[record.Id] = true
+		if record.Status == db.RequestSubmitted {
+			continue
+		}
+		delete(s.requestIds, record.Id)
+		err := s.send(UpdateRequestMessage{Type: "update_request", UpdateStatusResponse: makeUpdateStatusResponse(record)})
+		if err != nil {
+			return err
+		}
+	}
+	for _, id := range ids {
+		if checked[id] {
+			continue
+		}
+		checked[id] = true
+		delete(s.requestIds, id)
+		if err := s.sendError(fmt.Errorf("update request %d: %w", id, db.ErrNoSuchRequest)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *wsSession) handleMessage(message clientMessage) error {
+	if message.err != nil {
+		return s.sendError(message.err)
+	}
+
+	var pairs []db.CurrencyPair
+	for _, code := range message.message.Pairs {
+		currency1, currency2, err := utils.ParseCurrencyPair(code)
+		if err != nil {
+			return s.sendError(err)
+		}
+		pairs = append(pairs, db.CurrencyPair{Currency1: currency1, Currency2: currency2})
+	}
+
+	switch message.message.Action {
+	case "subscribe":
+		added := make(map[uint64]bool)
+		for _, id := range message.message.UpdateRequestIds {
+			if !s.requestIds[id] {
+				added[id] = true
+			}
+		}
+		if len(s.requestIds)+len(added) > constants.MaxFollowedRequests {
+			return s.sendError(fmt.Errorf("at most %d update requests can be followed", constants.MaxFollowedRequests))
+		}
+		for _, pair := range pairs {
+			s.pairs[pair] = true
+		}
+		for _, id := range message.message.UpdateRequestIds {
+			s.requestIds[id] = true
+		}
+	case "unsubscribe":
+		for _, pair := range pairs {
+			delete(s.pairs, pair)
+		}
+		for _, id := range message.message.UpdateRequestIds {
+			delete(s.requestIds, id)
+		}
+	default:
+		return s.sendError(fmt.Errorf("unknown action %q, expected subscribe or unsubscribe", message.message.Action))
+	}
+
+	if message.message.Action == "subscribe" {
+		// Requests that finished before the subscription are reported right away.
+		if err := s.checkRequests(message.message.UpdateRequestIds); err != nil {
+			return err
+		}
+	}
+	return s.sendSubscriptions()
+}
+
+func (s *wsSession) sendSubscriptions() error {
+	message := SubscriptionsMessage{Type: "subscriptions", Pairs: []string{}, UpdateRequestIds: []uint64{}}
+	for pair := range s.pairs {
+		message.Pairs = append(message.Pairs, pair.Currency1+"/"+pair.Currency2)
+	}
+	for id := range s.requestIds {
+		message.UpdateRequestIds = append(message.UpdateRequestIds, id)
+	}
+	slices.Sort(message.Pairs)
+	slices.Sort(message.UpdateRequestIds)
+	return s.send(message)
+}
@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/artem98/ExchangeRateService/server/constants"
+	"github.com/artem98/ExchangeRateService/server/rates/db"
+	"github.com/artem98/ExchangeRateService/server/rates/events"
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+	"github.com/shopspring/decimal"
+)
+
+// wsMessage has the fields of every message the server sends.
+type wsMessage struct {
+	Type             string           `json:"type"`
+	Pairs            []string         `json:"pairs"`
+	UpdateRequestIds []uint64         `json:"update_request_ids"`
+	CurrencyPair     string           `json:"currency_pair"`
+	Rate             json.Number      `json:"rate"`
+	UpdateID         uint64           `json:"update_request_id"`
+	Status           db.RequestStatus `json:"status"`
+	Error            any              `json:"error"`
+}
+
+type requestTable struct {
+	mu       sync.Mutex
+	requests map[uint64]db.UpdateRequestRecord
+	queries  []int
+}
+
+func (t *requestTable) get(ids []uint64) ([]db.UpdateRequestRecord, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.queries = append(t.queries, len(ids))
+	var records []db.UpdateRequestRecord
+	for _, id := range ids {
+		if record, ok := t.requests[id]; ok {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+func (t *requestTable) set(record db.UpdateRequestRecord) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.requests[record.Id] = record
+}
+
+func dialWebSocket(t *testing.T, handler *Handler) *websocket.Conn {
+	t.Helper()
+	r := chi.NewRouter()
+	r.Route("/ws", handler.HandleWebSocket)
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func readMessage(t *testing.T, conn *websocket.Conn) wsMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var message wsMessage
+	if err := conn.ReadJSON(&message); err != nil {
+		t.Fatalf("failed to read message: %v", err)
+	}
+	return message
+}
+
+func TestWebSocketSubscriptions(t *testing.T) {
+	table := &requestTable{requests: map[uint64]db.UpdateRequestRecord{
+		42: {Id: 42, Currency1: "EUR", Currency2: "USD", Status: db.RequestSubmitted, CreatedAt: time.Now()},
+	}}
+	broker, publish := makeBroker(t)
+	handler := &Handler{Db: &mockDb{getUpdateRequests: table.get}, Events: broker}
+	conn := dialWebSocket(t, handler)
+
+	conn.WriteJSON(SubscriptionMessage{Action: "subscribe", Pairs: []string{"eur/usd"}, UpdateRequestIds: []uint64{42}})
+	ack := readMessage(t, conn)
+	if ack.Type != "subscriptions" || len(ack.Pairs) != 1 || ack.Pairs[0] != "EUR/USD" || len(ack.UpdateRequestIds) != 1 {
+		t.Fatalf("unexpected ack %+v", ack)
+	}
+
+	publish("GBP", "USD", db.RateRecord{Rate: decimal.RequireFromString("1.27")})
+	publish("EUR", "USD", db.RateRecord{Rate: decimal.RequireFromString("1.08")})
+	rate := readMessage(t, conn)
+	if rate.Type != "rate" || rate.CurrencyPair != "EUR/USD" || rate.Rate != "1.08" {
+		t.Fatalf("unexpected rate message %+v", rate)
+	}
+
+	table.set(db.UpdateRequestRecord{
+		Id: 42, Currency1: "EUR", Currency2: "USD", Status: db.RequestOk, CreatedAt: time.Now(), CompletedAt: time.Now(),
+		Rate: db.RateRecord{Rate: decimal.RequireFromString("1.08")},
+	})
+	broker.PublishRequest(events.RequestEvent{RequestId: 42, Status: db.RequestOk})
+	finished := readMessage(t, conn)
+	if finished.Type != "update_request" || finished.UpdateID != 42 || finished.Status != db.RequestOk || finished.Rate != "1.08" {
+		t.Fatalf("unexpected request message %+v", finished)
+	}
+
+	conn.WriteJSON(SubscriptionMessage{Action: "unsubscribe", Pairs: []string{"EUR/USD"}})
+	ack = readMessage(t, conn)
+	if ack.Type != "subscriptions" || len(ack.Pairs) != 0 || len(ack.UpdateRequestIds) != 0 {
+		t.Fatalf("unexpected ack %+v", ack)
+	}
+}
+
+func TestWebSocketRequestsFinishedElsewhere(t *testing.T) {
+	table := &requestTable{requests: map[uint64]db.UpdateRequestRecord{
+		42: {Id: 42, Currency1: "EUR", Currency2: "USD", Status: db.RequestSubmitted, CreatedAt: time.Now()},
+	}}
+	broker, _ := makeBroker(t)
+	handler := &Handler{Db: &mockDb{getUpdateRequests: table.get}, Events: broker}
+	conn := dialWebSocket(t, handler)
+
+	conn.WriteJSON(SubscriptionMessage{Action: "subscribe", UpdateRequestIds: []uint64{42}})
+	if ack := readMessage(t, conn); ack.Type != "subscriptions" {
+		t.Fatalf("unexpected ack %+v", ack)
+	}
+
+	// Finished by another server, so no request event is published here.
+	table.set(db.UpdateRequestRecord{Id: 42, Currency1: "EUR", Currency2: "USD", Status: db.RequestFailed, FailureReason: "provider timeout"})
+	finished := readMessage(t, conn)
+	if finished.Type != "update_request" || finished.UpdateID != 42 || finished.Status != db.RequestFailed {
+		t.Fatalf("unexpected request message %+v", finished)
+	}
+}
+
+func TestWebSocketFinishedAndUnknownRequests(t *testing.T) {
+	table := &requestTable{requests: map[uint64]db.UpdateRequestRecord{
+		7: {Id: 7, Currency1: "EUR", Currency2: "USD", Status: db.RequestFailed, FailureReason: "provider timeout"},
+	}}
+	broker, _ := makeBroker(t)
+	handler := &Handler{Db: &mockDb{getUpdateRequests: table.get}, Events: broker}
+	conn := dialWebSocket(t, handler)
+
+	conn.WriteJSON(SubscriptionMessage{Action: "subscribe", UpdateRequestIds: []uint64{7, 8}})
+
+	failed := readMessage(t, conn)
+	if failed.Type != "update_request" || failed.Status != db.RequestFailed {
+		t.Fatalf("unexpected request message %+v", failed)
+	}
+	if reason, _ := failed.Error.(map[string]any)["reason"].(string); reason != "provider timeout" {
+		t.Errorf("expected failure reason, got %v", failed.Error)
+	}
+
+	unknown := readMessage(t, conn)
+	if unknown.Type != "error" || !strings.Contains(unknown.Error.(string), "update request 8") {
+		t.Fatalf("unexpected error message %+v", unknown)
+	}
+
+	ack := readMessage(t, conn)
+	if ack.Type != "subscriptions" || len(ack.UpdateRequestIds) != 0 {
+		t.Fatalf("unexpected ack %+v", ack)
+	}
+}
+
+func TestWebSocketLimitsFollowedRequests(t *testing.T) {
+	table := &requestTable{requests: map[uint64]db.UpdateRequestRecord{}}
+	var ids []uint64
+	for id := uint64(1); id <= constants.MaxFollowedRequests; id++ {
+		table.requests[id] = db.UpdateRequestRecord{Id: id, Currency1: "EUR", Currency2: "USD", Status: db.RequestSubmitted}
+		ids = append(ids, id)
+	}
+	broker, _ := makeBroker(t)
+	handler := &Handler{Db: &mockDb{getUpdateRequests: table.get}, Events: broker}
+	conn := dialWebSocket(t, handler)
+
+	conn.WriteJSON(SubscriptionMessage{Action: "subscribe", UpdateRequestIds: ids})
+	if ack := readMessage(t, conn); ack.Type != "subscriptions" || len(ack.UpdateRequestIds) != constants.MaxFollowedRequests {
+		t.Fatalf("unexpected ack %+v", ack)
+	}
+	table.mu.Lock()
+	if !slices.Equal(table.queries, []int{constants.MaxFollowedRequests}) {
+		t.Errorf("expected the requests to be read in one query, got %v", table.queries)
+	}
+	table.mu.Unlock()
+
+	conn.WriteJSON(SubscriptionMessage{Action: "subscribe", UpdateRequestIds: []uint64{1, constants.MaxFollowedRequests + 1}})
+	if reply := readMessage(t, conn); reply.Type != "error" {
+		t.Fatalf("expected error, got %+v", reply)
+	}
+}
+
+func TestWebSocketClosesOnLargeMessages(t *testing.T) {
+	broker, _ := makeBroker(t)
+	handler := &Handler{Db: &mockDb{}, Events: broker}
+	conn := dialWebSocket(t, handler)
+
+	message := `{"action":"subscribe","pairs":["` + strings.Repeat("A", constants.WebSocketReadLimit) + `"]}`
+	conn.WriteMessage(websocket.TextMessage, []byte(message))
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseMessageTooBig) {
+		t.Fatalf("expected the connection to be closed, got %v", err)
+	}
+}
+
+func TestWebSocketRefusesOtherOrigins(t *testing.T) {
+	broker, _ := makeBroker(t)
+	handler := &Handler{Db: &mockDb{}, Events: broker}
+	r := chi.NewRouter()
+	r.Route("/ws", handler.HandleWebSocket)
+	server := httptest.NewServer(r)
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+
+	_, resp, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {"https://example.com"}})
+	if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 for another origin, got %v", err)
+	}
+	conn, _, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {server.URL}})
+	if err != nil {
+		t.Fatalf("expected the same origin to connect, got %v", err)
+	}
+	conn.Close()
+}
+
+func TestWebSocketBadMessages(t *testing.T) {
+	broker, _ := makeBroker(t)
+	handler := &Handler{Db: &mockDb{}, Events: broker}
+	conn := dialWebSocket(t, handler)
+
+	for _, message := range []string{`{"action":`, `{"action":"watch"}`, `{"action":"subscribe","pairs":["EURUSD"]}`} {
+		conn.WriteMessage(websocket.TextMessage, []byte(message))
+		reply := readMessage(t, conn)
+		if reply.Type != "error" {
+			t.Errorf("%s: expected error, got %+v", message, reply)
+		}
+	}
+}
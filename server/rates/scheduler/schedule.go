@@ -0,0 +1,156 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule tells when something happens next.
+type Schedule interface {
+	// Next returns the first time strictly after the given one, or the zero
+	// time when there is none.
+	Next(after time.Time) time.Time
+}
+
+// Every fires at every multiple of its duration, so "@every 1m" fires at the
+// start of every minute.
+type Every time.Duration
+
+func (e Every) Next(after time.Time) time.Time {
+	d := time.Duration(e)
+	return after.Truncate(d).Add(d)
+}
+
+// Cron is a standard five field cron expression: minute, hour, day of month,
+// month and day of week. Fields accept *, numbers, ranges, lists and steps,
+// e.g. "*/15 9-17 * * 1-5". Times are matched in the location of the time
+// passed to Next.
+type Cron struct {
+	minute, hour, dayOfMonth, month, dayOfWeek uint64
+	// Like in cron, a day matches either day field when both are restricted.
+	anyDayOfMonth, anyDayOfWeek bool
+}
+
+// ParseSchedule parses a cron expression, "@every <duration>", "@hourly" or "@daily".
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	switch {
+	case spec == "@hourly":
+		spec = "0 * * * *"
+	case spec == "@daily":
+		spec = "0 0 * * *"
+	case strings.HasPrefix(spec, "@every "):
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil || d < time.Second {
+			return nil, fmt.Errorf("invalid schedule %q: expected a duration of at least 1s", spec)
+		}
+		return Every(d), nil
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: expected 5 cron fields, @every, @hourly or @daily", spec)
+	}
+
+	var c Cron
+	var err error
+	if c.minute, err = parseField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: minute: %w", spec, err)
+	}
+	if c.hour, err = parseField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: hour: %w", spec, err)
+	}
+	if c.dayOfMonth, err = parseField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: day of month: %w", spec, err)
+	}
+	if c.month, err = parseField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: month: %w", spec, err)
+	}
+	if c.dayOfWeek, err = parseField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: day of week: %w", spec, err)
+	}
+	// Both 0 and 7 are Sunday.
+	if c.dayOfWeek&(1<<7) != 0 {
+		c.dayOfWeek |= 1
+	}
+	c.anyDayOfMonth = fields[2] == "*"
+	c.anyDayOfWeek = fields[4] == "*"
+	return c, nil
+}
+
+func parseField(field string, low, high int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		valueRange, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepText); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepText)
+			}
+		}
+
+		first, last := low, high
+		if valueRange != "*" {
+			fromText, toText, isRange := strings.Cut(valueRange, "-")
+			from, err := strconv.Atoi(fromText)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", fromText)
+			}
+			first, last = from, from
+			if isRange {
+				if last, err = strconv.Atoi(toText); err != nil {
+					return 0, fmt.Errorf("invalid value %q", toText)
+				}
+			} else if hasStep {
+				last = high
+			}
+		}
+		if first < low || last > high || first > last {
+			return 0, fmt.Errorf("%q is out of range %d-%d", part, low, high)
+		}
+
+		for value := first; value <= last; value += step {
+			bits |= 1 << value
+		}
+	}
+	return bits, nil
+}
+
+func has(bits uint64, value int) bool {
+	return bits&(1<<value) != 0
+}
+
+func (c Cron) dayMatches(t time.Time) bool {
+	dayOfMonth := has(c.dayOfMonth, t.Day())
+	dayOfWeek := has(c.dayOfWeek, int(t.Weekday()))
+	if c.anyDayOfMonth || c.anyDayOfWeek {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
+
+func (c Cron) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	// Every valid expression matches within a few years.
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		year, month, day := t.Date()
+		switch {
+		case !has(c.month, int(month)):
+			t = time.Date(year, month+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(year, month, day+1, 0, 0, 0, 0, t.Location())
+		case !has(c.hour, t.Hour()):
+			t = time.Date(year, month, day, t.Hour()+1, 0, 0, 0, t.Location())
+		case !has(c.minute, t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,60 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func date(text string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", text)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseSchedule_Next(t *testing.T) {
+	cases := []struct {
+		spec  string
+		after string
+		want  string
+	}{
+		{"@every 1m", "2025-03-14 10:15", "2025-03-14 10:16"},
+		{"@every 15m", "2025-03-14 10:16", "2025-03-14 10:30"},
+		{"@hourly", "2025-03-14 10:15", "2025-03-14 11:00"},
+		{"@daily", "2025-03-14 10:15", "2025-03-15 00:00"},
+		{"*/20 9-17 * * *", "2025-03-14 17:45", "2025-03-15 09:00"},
+		{"0 15 * * 1-5", "2025-03-14 15:00", "2025-03-17 15:00"},
+		{"0 15 * * 1-5", "2025-03-17 14:59", "2025-03-17 15:00"},
+		{"30 6 1 */3 *", "2025-03-14 10:15", "2025-04-01 06:30"},
+		{"0 0 13 * 5", "2025-03-01 00:00", "2025-03-07 00:00"},
+		{"0 0 * * 7", "2025-03-14 10:15", "2025-03-16 00:00"},
+	}
+	for _, c := range cases {
+		schedule, err := ParseSchedule(c.spec)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.spec, err)
+		}
+		if got := schedule.Next(date(c.after)); !got.Equal(date(c.want)) {
+			t.Errorf("%s after %s: expected %s, got %s", c.spec, c.after, c.want, got.Format("2006-01-02 15:04"))
+		}
+	}
+}
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "5-1 * * * *", "*/0 * * * *", "@every soon", "@every 1ms", "@weekly"} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestCron_NextNeverFires(t *testing.T) {
+	schedule, err := ParseSchedule("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if next := schedule.Next(date("2025-03-14 10:15")); !next.IsZero() {
+		t.Errorf("expected February 30th never to come, got %s", next)
+	}
+}
@@ -0,0 +1,233 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/artem98/ExchangeRateService/server/constants"
+	"github.com/artem98/ExchangeRateService/server/rates/db"
+	"github.com/artem98/ExchangeRateService/server/rates/utils"
+)
+
+// Requester places update requests. Going through the same path as the API
+// lets scheduled refreshes share the job cache with manual ones, so a pair is
+// not fetched twice when both happen close together.
+type Requester interface {
+	RequestUpdate(currency1, currency2, callbackUrl string) (requestId uint64, deduplicated bool, err error)
+}
+
+// RateSource returns the stored rate of a pair.
+type RateSource interface {
+	GetRateByPair(currency1, currency2 string) (db.RateRecord, error)
+}
+
+// Claims makes sure one server refreshes a pair per tick when several run the
+// same schedules.
+type Claims interface {
+	// ClaimRefresh reports whether this server refreshes the pair, keeping the
+	// others from doing so for hold.
+	ClaimRefresh(pair db.CurrencyPair, hold time.Duration) (bool, error)
+}
+
+// Entry refreshes a set of pairs on a schedule.
+type Entry struct {
+	Pairs    []db.CurrencyPair
+	Spec     string
+	Schedule Schedule
+}
+
+// ParseEntries parses entries written as "<pairs>=<schedule>" and separated by
+// semicolons, e.g. "EUR/USD,GBP/USD=@every 1m; USD/MXN=0 * * * *".
+func ParseEntries(spec string) ([]Entry, error) {
+	var entries []Entry
+	for _, text := range strings.Split(spec, ";") {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		pairsText, scheduleText, found := strings.Cut(text, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid refresh schedule %q: expected <pairs>=<schedule>", text)
+		}
+
+		entry := Entry{Spec: strings.TrimSpace(scheduleText)}
+		for _, code := range strings.Split(pairsText, ",") {
+			currency1, currency2, err := utils.ParseCurrencyPair(strings.TrimSpace(code))
+			if err != nil {
+				return nil, fmt.Errorf("invalid refresh schedule %q: %w", text, err)
+			}
+			entry.Pairs = append(entry.Pairs, db.CurrencyPair{Currency1: currency1, Currency2: currency2})
+		}
+
+		schedule, err := ParseSchedule(entry.Spec)
+		if err != nil {
+			return nil, err
+		}
+		entry.Schedule = schedule
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Scheduler refreshes the pairs of its entries when their schedules fire.
+// When Publication is set, it tells when the upstream publishes new rates, and
+// a pair whose stored rate is newer than the latest publication is skipped, as
+// fetching it again would return the same rate. Schedules are evaluated in UTC.
+// Entries and Publication are changed with Reload once the scheduler runs.
+// Pairs refreshed on a schedule are claimed through Claims first, unless it is
+// nil.
+type Scheduler struct {
+	Entries     []Entry
+	Requester   Requester
+	Rates       RateSource
+	Claims      Claims
+	Publication Schedule
+
+	mu sync.Mutex
+	// ctx is the context of Run, stop ends the goroutines of the current
+	// entries and running counts the goroutines of all entries.
+	ctx     context.Context
+	stop    context.CancelFunc
+	running sync.WaitGroup
+	now     func() time.Time
+}
+
+func MakeScheduler(entries []Entry, requester Requester, rates RateSource, claims Claims, publication Schedule) *Scheduler {
+	return &Scheduler{
+		Entries:     entries,
+		Requester:   requester,
+		Rates:       rates,
+		Claims:      claims,
+		Publication: publication,
+		now:         func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Run refreshes the entries until ctx is done, and returns once the
+// refreshes in progress have finished.
+func (s *Scheduler) Run(ctx context.Context) {
+	s.mu.Lock()
+	s.ctx = ctx
+	s.startEntries()
+	s.mu.Unlock()
+
+	<-ctx.Done()
+	// Later reloads only replace the entries.
+	s.mu.Lock()
+	s.ctx = nil
+	s.mu.Unlock()
+	s.running.Wait()
+}
+
+// Reload replaces the entries and the publication schedule. Refreshes in
+// progress finish, and the new entries wait for their next time.
+func (s *Scheduler) Reload(entries []Entry, publication Schedule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Entries = entries
+	s.Publication = publication
+	if s.ctx != nil {
+		s.stop()
+		s.startEntries()
+	}
+}
+
+func (s *Scheduler) startEntries() {
+	var entriesCtx context.Context
+	entriesCtx, s.stop = context.WithCancel(s.ctx)
+	s.running.Add(len(s.Entries))
+	for _, entry := range s.Entries {
+		go func() {
+			defer s.running.Done()
+			s.runEntry(entriesCtx, entry)
+		}()
+	}
+}
+
+func (s *Scheduler) runEntry(ctx context.Context, entry Entry) {
+	for {
+		next := entry.Schedule.Next(s.now())
+		if next.IsZero() {
+			fmt.Printf("Refresh schedule %q never fires again\n", entry.Spec)
+			return
+		}
+
+		timer := time.NewTimer(next.Sub(s.now()))
+		select {
+		case <-timer.C:
+			s.fire(entry, next)
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// fire refreshes the pairs of entry at the tick fired that no other server
+// has claimed. The claims last until shortly before the following tick, so
+// servers whose ticks are apart by less than that refresh a pair once. They
+// last at least constants.MinRefreshClaimHold, which also covers a tick with
+// none following and spaces out refreshes scheduled more often.
+func (s *Scheduler) fire(entry Entry, fired time.Time) {
+	pairs := entry.Pairs
+	if s.Claims != nil {
+		hold := constants.MinRefreshClaimHold
+		if following := entry.Schedule.Next(fired); !following.IsZero() {
+			hold = max(hold, following.Sub(fired)*9/10)
+		}
+
+		pairs = nil
+		for _, pair := range entry.Pairs {
+			claimed, err := s.Claims.ClaimRefresh(pair, hold)
+			if err != nil {
+				// The next run tries again.
+				fmt.Printf("Failed to claim scheduled refresh of %s/%s: %s\n", pair.Currency1, pair.Currency2, err.Error())
+				continue
+			}
+			if claimed {
+				pairs = append(pairs, pair)
+			}
+		}
+	}
+	s.Refresh(pairs)
+}
+
+// Refresh requests an update of every pair that may have a newer rate upstream.
+// It returns how many requests were placed or deduplicated.
+func (s *Scheduler) Refresh(pairs []db.CurrencyPair) int {
+	requested := 0
+	for _, pair := range pairs {
+		if s.isUpToDate(pair) {
+			continue
+		}
+		_, _, err := s.Requester.RequestUpdate(pair.Currency1, pair.Currency2, "")
+		if err != nil {
+			// The next run tries again.
+			fmt.Printf("Scheduled refresh of %s/%s failed: %s\n", pair.Currency1, pair.Currency2, err.Error())
+			continue
+		}
+		requested++
+	}
+	return requested
+}
+
+func (s *Scheduler) isUpToDate(pair db.CurrencyPair) bool {
+	s.mu.Lock()
+	publication := s.Publication
+	s.mu.Unlock()
+	if publication == nil {
+		return false
+	}
+	record, err := s.Rates.GetRateByPair(pair.Currency1, pair.Currency2)
+	if err != nil {
+		if !errors.Is(err, db.ErrNoSuchPair) {
+			fmt.Println("Failed to read rate for scheduled refresh:", err)
+		}
+		return false
+	}
+	return publication.Next(record.UpdateTime.UTC()).After(s.now())
+}
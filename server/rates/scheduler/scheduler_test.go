@@ -0,0 +1,273 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/artem98/ExchangeRateService/server/rates/db"
+)
+
+type recordingRequester struct {
+	requested []string
+	err       error
+}
+
+func (r *recordingRequester) RequestUpdate(currency1, currency2, callbackUrl string) (uint64, bool, error) {
+	r.requested = append(r.requested, currency1+"/"+currency2)
+	return 1, false, r.err
+}
+
+type storedRates map[string]time.Time
+
+func (s storedRates) GetRateByPair(currency1, currency2 string) (db.RateRecord, error) {
+	updated, ok := s[currency1+"/"+currency2]
+	if !ok {
+		return db.RateRecord{}, db.ErrNoSuchPair
+	}
+	return db.RateRecord{UpdateTime: updated}, nil
+}
+
+func TestParseEntries(t *testing.T) {
+	entries, err := ParseEntries("eur/usd, GBP/USD=@every 1m; USD/MXN=0 * * * *;")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if len(entries[0].Pairs) != 2 || entries[0].Pairs[0] != (db.CurrencyPair{Currency1: "EUR", Currency2: "USD"}) {
+		t.Errorf("unexpected pairs %v", entries[0].Pairs)
+	}
+	if entries[1].Spec != "0 * * * *" {
+		t.Errorf("unexpected spec %q", entries[1].Spec)
+	}
+
+	for _, spec := range []string{"EUR/USD", "EURUSD=@hourly", "EUR/USD=every minute"} {
+		if _, err := ParseEntries(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestScheduler_RefreshSkipsPairsWithoutNewPublication(t *testing.T) {
+	publication, _ := ParseSchedule("0 15 * * 1-5")
+	rates := storedRates{
+		// Stored after Friday's publication, nothing new until Monday.
+		"EUR/USD": date("2025-03-14 15:30"),
+		// Stored before Friday's publication.
+		"GBP/USD": date("2025-03-14 09:00"),
+	}
+	requester := &recordingRequester{}
+	s := MakeScheduler(nil, requester, rates, nil, publication)
+	s.now = func() time.Time { return date("2025-03-15 12:00") }
+
+	pairs := []db.CurrencyPair{
+		{Currency1: "EUR", Currency2: "USD"},
+		{Currency1: "GBP", Currency2: "USD"},
+		{Currency1: "USD", Currency2: "MXN"},
+	}
+	if requested := s.Refresh(pairs); requested != 2 {
+		t.Errorf("expected 2 requests, got %d", requested)
+	}
+	if len(requester.requested) != 2 || requester.requested[0] != "GBP/USD" || requester.requested[1] != "USD/MXN" {
+		t.Errorf("expected GBP/USD and USD/MXN to be refreshed, got %v", requester.requested)
+	}
+}
+
+func TestScheduler_RefreshWithoutPublicationSchedule(t *testing.T) {
+	requester := &recordingRequester{err: errors.New("queue full")}
+	s := MakeScheduler(nil, requester, storedRates{"EUR/USD": time.Now()}, nil, nil)
+
+	if requested := s.Refresh([]db.CurrencyPair{{Currency1: "EUR", Currency2: "USD"}}); requested != 0 {
+		t.Errorf("expected failed request not to be counted, got %d", requested)
+	}
+	if len(requester.requested) != 1 {
+		t.Errorf("expected the pair to be requested regardless of its age")
+	}
+}
+
+type channelRequester chan string
+
+func (r channelRequester) RequestUpdate(currency1, currency2, callbackUrl string) (uint64, bool, error) {
+	r <- currency1 + "/" + currency2
+	return 1, false, nil
+}
+
+// soon fires a millisecond after every time.
+type soon struct{}
+
+func (soon) Next(after time.Time) time.Time {
+	return after.Add(time.Millisecond)
+}
+
+func TestScheduler_ReloadReplacesRunningEntries(t *testing.T) {
+	requester := make(channelRequester)
+	entry := func(currency1, currency2 string) Entry {
+		return Entry{Pairs: []db.CurrencyPair{{Currency1: currency1, Currency2: currency2}}, Schedule: soon{}}
+	}
+	s := MakeScheduler([]Entry{entry("EUR", "USD")}, requester, storedRates{}, nil, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+	next := func() string {
+		t.Helper()
+		select {
+		case pair := <-requester:
+			return pair
+		case <-time.After(time.Second):
+			t.Fatal("expected a refresh")
+			return ""
+		}
+	}
+	if pair := next(); pair != "EUR/USD" {
+		t.Fatalf("expected EUR/USD to be refreshed, got %s", pair)
+	}
+
+	reloaded := make(chan struct{})
+	go func() {
+		s.Reload([]Entry{entry("GBP", "USD")}, nil)
+		close(reloaded)
+	}()
+	for waiting := true; waiting; {
+		select {
+		case <-requester:
+		case <-reloaded:
+			waiting = false
+		}
+	}
+
+	// The old entry may still finish a refresh it started before the reload.
+	old := 0
+	for i := 0; i < 4; i++ {
+		switch pair := next(); pair {
+		case "EUR/USD":
+			old++
+		case "GBP/USD":
+		default:
+			t.Fatalf("unexpected refresh of %s", pair)
+		}
+	}
+	if old > 1 {
+		t.Fatalf("expected the old entry to stop, it was refreshed %d more times", old)
+	}
+}
+
+// blockingRequester holds every request until release is closed.
+type blockingRequester struct {
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (r blockingRequester) RequestUpdate(currency1, currency2, callbackUrl string) (uint64, bool, error) {
+	select {
+	case r.entered <- struct{}{}:
+	default:
+	}
+	<-r.release
+	return 1, false, nil
+}
+
+func TestScheduler_RunReturnsOnceRefreshesFinish(t *testing.T) {
+	requester := blockingRequester{entered: make(chan struct{}, 1), release: make(chan struct{})}
+	entry := Entry{Pairs: []db.CurrencyPair{{Currency1: "EUR", Currency2: "USD"}}, Schedule: soon{}}
+	s := MakeScheduler([]Entry{entry}, requester, storedRates{}, nil, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	returned := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(returned)
+	}()
+
+	<-requester.entered
+	cancel()
+	select {
+	case <-returned:
+		t.Fatal("expected Run to wait for the refresh in progress")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(requester.release)
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		t.Fatal("expected Run to return once the refresh finished")
+	}
+}
+
+func TestScheduler_ReloadReplacesPublicationSchedule(t *testing.T) {
+	rates := storedRates{"EUR/USD": date("2025-03-14 15:30")}
+	requester := &recordingRequester{}
+	s := MakeScheduler(nil, requester, rates, nil, nil)
+	s.now = func() time.Time { return date("2025-03-15 12:00") }
+
+	publication, _ := ParseSchedule("0 15 * * 1-5")
+	s.Reload(nil, publication)
+	if requested := s.Refresh([]db.CurrencyPair{{Currency1: "EUR", Currency2: "USD"}}); requested != 0 {
+		t.Errorf("expected the reloaded publication schedule to skip EUR/USD, got %d requests", requested)
+	}
+}
+
+// memoryClaims is a claim table shared by schedulers, with a clock of its own.
+type memoryClaims struct {
+	mu    sync.Mutex
+	now   time.Time
+	until map[db.CurrencyPair]time.Time
+}
+
+func (c *memoryClaims) ClaimRefresh(pair db.CurrencyPair, hold time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.now.Before(c.until[pair]) {
+		return false, nil
+	}
+	c.until[pair] = c.now.Add(hold)
+	return true, nil
+}
+
+func TestScheduler_OneServerRefreshesPerTick(t *testing.T) {
+	start := date("2025-03-14 10:00")
+	claims := &memoryClaims{until: make(map[db.CurrencyPair]time.Time)}
+	every, _ := ParseSchedule("@every 1m")
+	entry := Entry{Pairs: []db.CurrencyPair{{Currency1: "EUR", Currency2: "USD"}}, Schedule: every}
+
+	first, second := &recordingRequester{}, &recordingRequester{}
+	firstServer := MakeScheduler(nil, first, storedRates{}, claims, nil)
+	secondServer := MakeScheduler(nil, second, storedRates{}, claims, nil)
+	// The second server ticks 20 seconds after the first.
+	for minute := 0; minute < 3; minute++ {
+		claims.now = start.Add(time.Duration(minute) * time.Minute)
+		firstServer.fire(entry, claims.now)
+		claims.now = claims.now.Add(20 * time.Second)
+		secondServer.fire(entry, claims.now)
+	}
+
+	if len(first.requested) != 3 || len(second.requested) != 0 {
+		t.Fatalf("expected the first server to refresh every tick alone, got %v and %v", first.requested, second.requested)
+	}
+}
+
+// lastTick never fires again.
+type lastTick struct{}
+
+func (lastTick) Next(after time.Time) time.Time {
+	return time.Time{}
+}
+
+func TestScheduler_LastTickIsClaimedToo(t *testing.T) {
+	claims := &memoryClaims{now: date("2025-03-14 10:00"), until: make(map[db.CurrencyPair]time.Time)}
+	entry := Entry{Pairs: []db.CurrencyPair{{Currency1: "EUR", Currency2: "USD"}}, Schedule: lastTick{}}
+
+	first, second := &recordingRequester{}, &recordingRequester{}
+	MakeScheduler(nil, first, storedRates{}, claims, nil).fire(entry, claims.now)
+	claims.now = claims.now.Add(time.Second)
+	MakeScheduler(nil, second, storedRates{}, claims, nil).fire(entry, claims.now)
+
+	if len(first.requested) != 1 || len(second.requested) != 0 {
+		t.Fatalf("expected one server to refresh the last tick, got %v and %v", first.requested, second.requested)
+	}
+}
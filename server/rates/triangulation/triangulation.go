@@ -0,0 +1,133 @@
+package triangulation
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/artem98/ExchangeRateService/server/rates/db"
+	"github.com/artem98/ExchangeRateService/server/rates/utils"
+	"github.com/shopspring/decimal"
+)
+
+const DerivedProviderName = "derived"
+
+type RateSource interface {
+	GetRateByPair(currency1, currency2 string) (db.RateRecord, error)
+}
+
+// Leg is a stored rate used to derive another one. Inverse means the stored
+// pair is quoted the other way round and its reciprocal was used.
+type Leg struct {
+	Currency1 string
+	Currency2 string
+	Inverse   bool
+	Record    db.RateRecord
+}
+
+// Triangulator resolves a pair from the stored rates: the direct quote when
+// there is one, otherwise the inverse of the opposite quote, otherwise a
+// product of two legs through one of the Pivots, tried in order.
+type Triangulator struct {
+	Source RateSource
+	Pivots []string
+}
+
+// Resolve returns the rate of currency1/currency2 and the legs it was derived
+// from. Legs are empty for a direct quote. A derived record carries the update
+// time of its oldest leg.
+func (t Triangulator) Resolve(currency1, currency2 string) (db.RateRecord, []Leg, error) {
+	record, err := t.Source.GetRateByPair(currency1, currency2)
+	if err == nil {
+		return record, nil, nil
+	}
+	if !errors.Is(err, db.ErrNoSuchPair) {
+		return db.RateRecord{}, nil, err
+	}
+
+	leg, err := t.findLeg(currency1, currency2, false)
+	if err == nil {
+		return derive(leg), []Leg{leg}, nil
+	}
+	if !errors.Is(err, db.ErrNoSuchPair) {
+		return db.RateRecord{}, nil, err
+	}
+
+	for _, pivot := range t.Pivots {
+		if pivot == currency1 || pivot == currency2 {
+			continue
+		}
+
+		first, err := t.findLeg(currency1, pivot, true)
+		if errors.Is(err, db.ErrNoSuchPair) {
+			continue
+		}
+		if err != nil {
+			return db.RateRecord{}, nil, err
+		}
+
+		second, err := t.findLeg(pivot, currency2, true)
+		if errors.Is(err, db.ErrNoSuchPair) {
+			continue
+		}
+		if err != nil {
+			return db.RateRecord{}, nil, err
+		}
+
+		return derive(first, second), []Leg{first, second}, nil
+	}
+
+	return db.RateRecord{}, nil, fmt.Errorf("%w: %s/%s cannot be derived via %v", db.ErrNoSuchPair, currency1, currency2, t.Pivots)
+}
+
+// findLeg looks up currency1/currency2 as stored (when direct is allowed) or as
+// the inverse of currency2/currency1.
+func (t Triangulator) findLeg(currency1, currency2 string, direct bool) (Leg, error) {
+	if direct {
+		record, err := t.Source.GetRateByPair(currency1, currency2)
+		if err == nil {
+			return Leg{Currency1: currency1, Currency2: currency2, Record: record}, nil
+		}
+		if !errors.Is(err, db.ErrNoSuchPair) {
+			return Leg{}, err
+		}
+	}
+
+	record, err := t.Source.GetRateByPair(currency2, currency1)
+	if err != nil {
+		return Leg{}, err
+	}
+	if record.Rate.IsZero() {
+		return Leg{}, fmt.Errorf("%w: %s/%s has zero rate", db.ErrNoSuchPair, currency2, currency1)
+	}
+	return Leg{Currency1: currency2, Currency2: currency1, Inverse: true, Record: record}, nil
+}
+
+// rate of the leg in the direction it is used. Reciprocals are cut to
+// decimal.DivisionPrecision digits.
+func (l Leg) rate() decimal.Decimal {
+	if l.Inverse {
+		return decimal.NewFromInt(1).Div(l.Record.Rate)
+	}
+	return l.Record.Rate
+}
+
+func derive(legs ...Leg) db.RateRecord {
+	result := db.RateRecord{Rate: decimal.NewFromInt(1), Provider: DerivedProviderName}
+	seen := make(map[string]bool)
+
+	for i, leg := range legs {
+		result.Rate = result.Rate.Mul(leg.rate())
+		if i == 0 || leg.Record.UpdateTime.Before(result.UpdateTime) {
+			result.UpdateTime = leg.Record.UpdateTime
+		}
+		for _, source := range leg.Record.Sources {
+			if !seen[source] {
+				seen[source] = true
+				result.Sources = append(result.Sources, source)
+			}
+		}
+	}
+	result.Rate = utils.TrimZeros(result.Rate)
+
+	return result
+}
@@ -0,0 +1,107 @@
+package triangulation
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/artem98/ExchangeRateService/server/rates/db"
+	"github.com/artem98/ExchangeRateService/server/rates/utils"
+	"github.com/shopspring/decimal"
+)
+
+type mapSource map[string]db.RateRecord
+
+func (m mapSource) GetRateByPair(currency1, currency2 string) (db.RateRecord, error) {
+	record, ok := m[currency1+"/"+currency2]
+	if !ok {
+		return db.RateRecord{}, db.ErrNoSuchPair
+	}
+	return record, nil
+}
+
+var (
+	older = time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	newer = time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+)
+
+func TestResolve_Direct(t *testing.T) {
+	tr := Triangulator{Source: mapSource{"EUR/USD": {Rate: decimal.RequireFromString("1.1"), UpdateTime: newer}}}
+
+	record, legs, err := tr.Resolve("EUR", "USD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if record.Rate.String() != "1.1" || len(legs) != 0 {
+		t.Errorf("expected direct 1.1, got %s via %v", record.Rate, legs)
+	}
+}
+
+func TestResolve_Inverse(t *testing.T) {
+	tr := Triangulator{Source: mapSource{"MXN/EUR": {Rate: decimal.RequireFromString("0.05"), UpdateTime: newer}}}
+
+	record, legs, err := tr.Resolve("EUR", "MXN")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if utils.FormatDecimal(record.Rate) != "20" {
+		t.Errorf("expected 20, got %s", utils.FormatDecimal(record.Rate))
+	}
+	if len(legs) != 1 || !legs[0].Inverse || legs[0].Currency1 != "MXN" {
+		t.Errorf("expected single inverse MXN/EUR leg, got %+v", legs)
+	}
+	if record.Provider != DerivedProviderName {
+		t.Errorf("expected derived provider, got %s", record.Provider)
+	}
+}
+
+func TestResolve_ViaPivot(t *testing.T) {
+	tr := Triangulator{
+		Source: mapSource{
+			"EUR/USD": {Rate: decimal.RequireFromString("1.1"), UpdateTime: newer, Sources: []string{"frankfurter"}},
+			"MXN/USD": {Rate: decimal.RequireFromString("0.05"), UpdateTime: older, Sources: []string{"erapi"}},
+		},
+		Pivots: []string{"GBP", "USD"},
+	}
+
+	record, legs, err := tr.Resolve("EUR", "MXN")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if utils.FormatDecimal(record.Rate) != "22" {
+		t.Errorf("expected 22, got %s", utils.FormatDecimal(record.Rate))
+	}
+	if !record.UpdateTime.Equal(older) {
+		t.Errorf("expected oldest leg time %v, got %v", older, record.UpdateTime)
+	}
+	if len(legs) != 2 || legs[0].Inverse || !legs[1].Inverse {
+		t.Errorf("expected EUR/USD and inverse MXN/USD legs, got %+v", legs)
+	}
+	if len(record.Sources) != 2 {
+		t.Errorf("expected sources of both legs, got %v", record.Sources)
+	}
+}
+
+func TestResolve_NotDerivable(t *testing.T) {
+	tr := Triangulator{Source: mapSource{"EUR/USD": {Rate: decimal.RequireFromString("1.1")}}, Pivots: []string{"USD"}}
+
+	_, _, err := tr.Resolve("GBP", "MXN")
+	if !errors.Is(err, db.ErrNoSuchPair) {
+		t.Errorf("expected ErrNoSuchPair, got %v", err)
+	}
+}
+
+type failingSource struct{}
+
+func (failingSource) GetRateByPair(currency1, currency2 string) (db.RateRecord, error) {
+	return db.RateRecord{}, errors.New("db down")
+}
+
+func TestResolve_SourceError(t *testing.T) {
+	tr := Triangulator{Source: failingSource{}, Pivots: []string{"USD"}}
+
+	_, _, err := tr.Resolve("EUR", "MXN")
+	if err == nil || errors.Is(err, db.ErrNoSuchPair) {
+		t.Errorf("expected source error, got %v", err)
+	}
+}
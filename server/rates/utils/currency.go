@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/artem98/ExchangeRateService/server/constants"
+	"github.com/shopspring/decimal"
+)
+
+// minorUnits lists ISO 4217 currencies whose minor unit differs from the usual 2 digits.
+var minorUnits = map[string]int32{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// MinorUnits returns the number of decimal digits amounts in the currency are expressed with.
+func MinorUnits(currency string) int32 {
+	if digits, ok := minorUnits[currency]; ok {
+		return digits
+	}
+	return 2
+}
+
+func ParseCurrencyCode(input string) (string, error) {
+	code := strings.ToUpper(input)
+	if len(code) != 3 || strings.IndexFunc(code, func(r rune) bool { return r < 'A' || r > 'Z' }) >= 0 {
+		return "", fmt.Errorf("invalid currency code %q: expected 3 letters", input)
+	}
+	return code, nil
+}
+
+// ParseAmount reads an amount of money. Its significant digits and exponent
+// are limited, as arithmetic on a number like 1e20000000 takes seconds and
+// megabytes.
+func ParseAmount(input string) (decimal.Decimal, error) {
+	// Longer inputs cannot be within the limits, and are not even parsed.
+	if len(input) > constants.MaxAmountDigits+8 {
+		return decimal.Decimal{}, fmt.Errorf("amount must have at most %d digits", constants.MaxAmountDigits)
+	}
+	amount, err := decimal.NewFromString(input)
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("amount must be a number")
+	}
+	if amount.NumDigits() > constants.MaxAmountDigits {
+		return decimal.Decimal{}, fmt.Errorf("amount must have at most %d digits", constants.MaxAmountDigits)
+	}
+	if exp := amount.Exponent(); exp > constants.MaxAmountExponent || exp < -constants.MaxAmountExponent {
+		return decimal.Decimal{}, fmt.Errorf("amount exponent must be between -%d and %d", constants.MaxAmountExponent, constants.MaxAmountExponent)
+	}
+	return amount, nil
+}
+
+type RoundingMode string
+
+const (
+	RoundHalfEven RoundingMode = "half-even"
+	RoundHalfUp   RoundingMode = "half-up"
+	RoundDown     RoundingMode = "down"
+)
+
+func ParseRoundingMode(input string) (RoundingMode, error) {
+	switch mode := RoundingMode(strings.ToLower(input)); mode {
+	case RoundHalfEven, RoundHalfUp, RoundDown:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid rounding mode %q: expected %s, %s or %s", input, RoundHalfEven, RoundHalfUp, RoundDown)
+	}
+}
+
+// Round rounds value to the given number of decimal places. Half-up rounds
+// halves away from zero and down truncates towards zero.
+func Round(value decimal.Decimal, places int32, mode RoundingMode) decimal.Decimal {
+	switch mode {
+	case RoundHalfUp:
+		return value.Round(places)
+	case RoundDown:
+		return value.RoundDown(places)
+	default:
+		return value.RoundBank(places)
+	}
+}
+
+// TrimZeros drops the trailing zeros of d, such as those decimal.Decimal.Div
+// pads its quotients to decimal.DivisionPrecision digits with.
+func TrimZeros(d decimal.Decimal) decimal.Decimal {
+	for d.Exponent() < 0 {
+		shorter := d.Truncate(-d.Exponent() - 1)
+		if !shorter.Equal(d) {
+			break
+		}
+		d = shorter
+	}
+	return d
+}
+
+// FormatDecimal renders d with every digit it was created with, so "1.0850"
+// stays "1.0850" where decimal.Decimal.String would print "1.085".
+func FormatDecimal(d decimal.Decimal) string {
+	if d.Exponent() >= 0 {
+		return d.String()
+	}
+	return d.StringFixed(-d.Exponent())
+}
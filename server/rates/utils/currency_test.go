@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestMinorUnits(t *testing.T) {
+	cases := map[string]int32{"USD": 2, "EUR": 2, "JPY": 0, "KWD": 3, "CLF": 4}
+	for currency, expected := range cases {
+		if got := MinorUnits(currency); got != expected {
+			t.Errorf("%s: expected %d, got %d", currency, expected, got)
+		}
+	}
+}
+
+func TestParseCurrencyCode(t *testing.T) {
+	code, err := ParseCurrencyCode("eur")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if code != "EUR" {
+		t.Errorf("expected EUR, got %s", code)
+	}
+
+	_, err = ParseCurrencyCode("EURO")
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "invalid currency code") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+
+	for _, input := range []string{"E1R", "EU ", "../", "ÉU"} {
+		if _, err := ParseCurrencyCode(input); err == nil {
+			t.Errorf("expected error for %q, got nil", input)
+		}
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	for _, input := range []string{"125.50", "-3", "1e30", "1E-30", "12345678901234567890123456789012345678"} {
+		if _, err := ParseAmount(input); err != nil {
+			t.Errorf("%s: unexpected error: %v", input, err)
+		}
+	}
+	for _, input := range []string{"ten", "1e20000000", "1e31", "1e-31", "123456789012345678901234567890123456789", strings.Repeat("9", 1000)} {
+		if _, err := ParseAmount(input); err == nil {
+			t.Errorf("expected error for %.40s", input)
+		}
+	}
+}
+
+func TestParseRoundingMode(t *testing.T) {
+	mode, err := ParseRoundingMode("HALF-UP")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mode != RoundHalfUp {
+		t.Errorf("expected %s, got %s", RoundHalfUp, mode)
+	}
+
+	if _, err := ParseRoundingMode("ceiling"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestRound(t *testing.T) {
+	cases := []struct {
+		value    string
+		places   int32
+		mode     RoundingMode
+		expected string
+	}{
+		{"0.125", 2, RoundHalfEven, "0.12"},
+		{"0.375", 2, RoundHalfEven, "0.38"},
+		{"2.675", 2, RoundHalfEven, "2.68"},
+		{"0.125", 2, RoundHalfUp, "0.13"},
+		{"-0.125", 2, RoundHalfUp, "-0.13"},
+		{"0.129", 2, RoundDown, "0.12"},
+		{"-0.129", 2, RoundDown, "-0.12"},
+		{"1234.5", 0, RoundHalfEven, "1234"},
+		{"1.23456", 3, RoundHalfUp, "1.235"},
+	}
+	for _, c := range cases {
+		got := Round(decimal.RequireFromString(c.value), c.places, c.mode)
+		if !got.Equal(decimal.RequireFromString(c.expected)) {
+			t.Errorf("Round(%s, %d, %s): expected %s, got %s", c.value, c.places, c.mode, c.expected, got)
+		}
+	}
+}
+
+func TestTrimZeros(t *testing.T) {
+	cases := map[string]string{
+		"1.0842500000000000": "1.08425",
+		"22.000":             "22",
+		"1200":               "1200",
+		"-0.50":              "-0.5",
+	}
+	for input, want := range cases {
+		if got := FormatDecimal(TrimZeros(decimal.RequireFromString(input))); got != want {
+			t.Errorf("expected %s for %s, got %s", want, input, got)
+		}
+	}
+}
+
+func TestFormatDecimal(t *testing.T) {
+	cases := []string{"1.0850", "33", "0.00012", "-4.50", "1200"}
+	for _, c := range cases {
+		if got := FormatDecimal(decimal.RequireFromString(c)); got != c {
+			t.Errorf("expected %s, got %s", c, got)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package utils
 import (
 	"fmt"
 	"strings"
+	"time"
 )
 
 func ParseCurrencyPair(input string) (string, string, error) {
@@ -22,3 +23,14 @@ func ParseCurrencyPair(input string) (string, string, error) {
 
 	return parts[0], parts[1], nil
 }
+
+// ParseTimestamp accepts an RFC 3339 timestamp or a plain "YYYY-MM-DD" date (midnight UTC).
+func ParseTimestamp(input string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, input); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, input); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q: expected RFC 3339 or YYYY-MM-DD", input)
+}
@@ -3,6 +3,7 @@ package utils
 import (
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestParseCurrencyPair_Valid(t *testing.T) {
@@ -74,3 +75,35 @@ func TestParseCurrencyPair_Empty(t *testing.T) {
 		t.Errorf("unexpected error message: %v", err)
 	}
 }
+
+func TestParseTimestamp_RFC3339(t *testing.T) {
+	ts, err := ParseTimestamp("2025-03-14T15:09:26+02:00")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := time.Date(2025, 3, 14, 13, 9, 26, 0, time.UTC)
+	if !ts.Equal(expected) {
+		t.Errorf("expected %v, got %v", expected, ts)
+	}
+}
+
+func TestParseTimestamp_Date(t *testing.T) {
+	ts, err := ParseTimestamp("2025-03-14")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)
+	if !ts.Equal(expected) {
+		t.Errorf("expected %v, got %v", expected, ts)
+	}
+}
+
+func TestParseTimestamp_Invalid(t *testing.T) {
+	_, err := ParseTimestamp("14/03/2025")
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "invalid timestamp") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+
+	"github.com/artem98/ExchangeRateService/server/constants"
+)
+
+// ErrPrivateAddress is returned for callback URLs that lead into the network
+// of the server or to any other address in blockedPrefixes.
+var ErrPrivateAddress = errors.New("callback address is not public")
+
+// blockedPrefixes are the special-purpose ranges of the IANA registries that
+// are not reachable on the internet, or reach it only through a translator
+// that may lead back into a private network.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // this network
+	netip.MustParsePrefix("10.0.0.0/8"),      // private
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier-grade NAT
+	netip.MustParsePrefix("127.0.0.0/8"),     // loopback
+	netip.MustParsePrefix("169.254.0.0/16"),  // link-local
+	netip.MustParsePrefix("172.16.0.0/12"),   // private
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentation
+	netip.MustParsePrefix("192.88.99.0/24"),  // 6to4 relay anycast
+	netip.MustParsePrefix("192.168.0.0/16"),  // private
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentation
+	netip.MustParsePrefix("224.0.0.0/4"),     // multicast
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved and broadcast
+	netip.MustParsePrefix("::/128"),          // unspecified
+	netip.MustParsePrefix("::1/128"),         // loopback
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // local NAT64
+	netip.MustParsePrefix("100::/64"),        // discard
+	netip.MustParsePrefix("2001::/32"),       // Teredo
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+	netip.MustParsePrefix("2002::/16"),       // 6to4
+	netip.MustParsePrefix("fc00::/7"),        // unique local
+	netip.MustParsePrefix("fe80::/10"),       // link-local
+	netip.MustParsePrefix("fec0::/10"),       // site-local
+	netip.MustParsePrefix("ff00::/8"),        // multicast
+}
+
+// Resolver looks up the addresses of a host, as *net.Resolver does.
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// CheckCallbackUrl accepts absolute http and https URLs whose host only
+// resolves to public addresses.
+func CheckCallbackUrl(ctx context.Context, resolver Resolver, callbackUrl string) error {
+	parsed, err := url.Parse(callbackUrl)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return fmt.Errorf("callback_url must be an absolute http or https URL")
+	}
+
+	addresses, err := resolver.LookupIPAddr(ctx, parsed.Hostname())
+	if err != nil {
+		return fmt.Errorf("callback_url host cannot be resolved: %w", err)
+	}
+	for _, address := range addresses {
+		if !isPublic(address.IP) {
+			return fmt.Errorf("callback_url resolves to %s: %w", address.IP, ErrPrivateAddress)
+		}
+	}
+	return nil
+}
+
+func isPublic(ip net.IP) bool {
+	address, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return false
+	}
+	// IPv4-mapped IPv6 addresses are checked as the IPv4 address they carry.
+	address = address.Unmap()
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(address) {
+			return false
+		}
+	}
+	return true
+}
+
+// refusePrivate checks the address a callback is actually sent to, as a host
+// may resolve differently at delivery than when the request was placed.
+func refusePrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublic(ip) {
+		return fmt.Errorf("refusing to call %s: %w", host, ErrPrivateAddress)
+	}
+	return nil
+}
+
+// makeCallbackClient returns a client that only connects to public addresses
+// and reports redirects as responses instead of following them.
+func makeCallbackClient() *http.Client {
+	dialer := &net.Dialer{Timeout: constants.WebhookTimeout, Control: refusePrivate}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// Through a proxy the dialer would only see the address of the proxy.
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   constants.WebhookTimeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
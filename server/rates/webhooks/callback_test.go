@@ -0,0 +1,122 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type staticResolver map[string][]string
+
+func (r staticResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	ips, ok := r[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	var addresses []net.IPAddr
+	for _, ip := range ips {
+		addresses = append(addresses, net.IPAddr{IP: net.ParseIP(ip)})
+	}
+	return addresses, nil
+}
+
+func TestCheckCallbackUrl(t *testing.T) {
+	resolver := staticResolver{
+		"hooks.example.com": {"93.184.215.14", "2606:2800:21f:cb07:6820:80da:af6b:8b2c"},
+		"metadata.internal": {"169.254.169.254"},
+		"mixed.example.com": {"93.184.215.14", "10.0.0.5"},
+		"localhost":         {"127.0.0.1", "::1"},
+	}
+
+	if err := CheckCallbackUrl(context.Background(), resolver, "https://hooks.example.com/rates"); err != nil {
+		t.Fatalf("expected a public host to be accepted, got %v", err)
+	}
+	for _, callbackUrl := range []string{
+		"http://localhost:8081/admin/reload",
+		"http://metadata.internal/latest/meta-data",
+		"https://mixed.example.com/rates",
+	} {
+		if err := CheckCallbackUrl(context.Background(), resolver, callbackUrl); !errors.Is(err, ErrPrivateAddress) {
+			t.Errorf("expected %s to be refused, got %v", callbackUrl, err)
+		}
+	}
+	for _, callbackUrl := range []string{"hooks.example.com/rates", "ftp://hooks.example.com", "https://unknown.example.com"} {
+		if err := CheckCallbackUrl(context.Background(), resolver, callbackUrl); err == nil {
+			t.Errorf("expected %s to be rejected", callbackUrl)
+		}
+	}
+}
+
+func TestIsPublic(t *testing.T) {
+	for _, c := range []struct {
+		ip     string
+		public bool
+	}{
+		{"93.184.215.14", true},
+		{"100.63.255.255", true},
+		{"2606:2800:21f:cb07:6820:80da:af6b:8b2c", true},
+		{"0.0.0.0", false},
+		{"0.1.2.3", false},
+		{"10.1.2.3", false},
+		{"100.64.0.1", false},
+		{"100.127.255.254", false},
+		{"127.0.0.53", false},
+		{"169.254.169.254", false},
+		{"172.31.0.1", false},
+		{"192.0.0.170", false},
+		{"192.168.1.1", false},
+		{"198.18.0.1", false},
+		{"203.0.113.7", false},
+		{"224.0.0.251", false},
+		{"239.255.255.250", false},
+		{"240.0.0.1", false},
+		{"255.255.255.255", false},
+		{"::", false},
+		{"::1", false},
+		{"::ffff:10.0.0.1", false},
+		{"::ffff:100.64.0.1", false},
+		{"64:ff9b::a00:1", false},
+		{"2001:db8::1", false},
+		{"2002:a00:1::1", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+		{"ff02::1", false},
+	} {
+		if public := isPublic(net.ParseIP(c.ip)); public != c.public {
+			t.Errorf("%s: expected public %v, got %v", c.ip, c.public, public)
+		}
+	}
+}
+
+func TestCallbackClient_RefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, err := makeCallbackClient().Get(server.URL)
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Fatalf("expected the loopback server to be refused, got %v", err)
+	}
+}
+
+func TestCallbackClient_DoesNotFollowRedirects(t *testing.T) {
+	client := makeCallbackClient()
+	// Only the redirect policy is under test, the loopback server must be reachable.
+	client.Transport = http.DefaultTransport
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data", http.StatusFound)
+	}))
+	defer server.Close()
+
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusFound {
+		t.Fatalf("expected the redirect to be returned, got %d", res.StatusCode)
+	}
+}
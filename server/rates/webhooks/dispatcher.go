@@ -0,0 +1,241 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/artem98/ExchangeRateService/server/constants"
+	"github.com/artem98/ExchangeRateService/server/rates/db"
+	"github.com/artem98/ExchangeRateService/server/rates/events"
+	"github.com/artem98/ExchangeRateService/server/rates/utils"
+)
+
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+)
+
+// Store is the part of the database the dispatcher needs.
+type Store interface {
+	GetUpdateRequest(id uint64) (db.UpdateRequestRecord, error)
+	RecordWebhookAttempt(attempt db.WebhookAttempt) error
+	ClaimWebhooks(limit int) ([]db.PendingWebhook, error)
+	RetryWebhook(requestId uint64, attempts int, delay time.Duration) error
+	FinishWebhook(requestId uint64, attempts int) error
+}
+
+type RequestSource interface {
+	SubscribeRequests() *events.RequestSubscription
+}
+
+// Payload is the body posted to the callback URL of a finished update request.
+type Payload struct {
+	UpdateID      uint64           `json:"update_request_id"`
+	Pair          string           `json:"pair"`
+	Status        db.RequestStatus `json:"status"`
+	Rate          json.Number      `json:"rate,omitempty"`
+	UpdateTime    *time.Time       `json:"update_time,omitempty"`
+	CompletedAt   time.Time        `json:"completed_at"`
+	FailureReason string           `json:"failure_reason,omitempty"`
+}
+
+// Dispatcher posts the outcome of every finished update request that has a
+// callback URL. Bodies are signed with HMAC-SHA256 over "<timestamp>.<body>",
+// the hex digest goes to SignatureHeader and the unix timestamp to
+// TimestampHeader. Failed deliveries are retried with exponential backoff and
+// every attempt is recorded in the store.
+//
+// The store keeps the callbacks that are due, finished requests make theirs
+// due. A retry is a later due time there rather than a pause of the dispatcher,
+// so callbacks are not lost when a server stops and any server may deliver
+// them. A callback may be delivered twice when a server stops during the post.
+type Dispatcher struct {
+	Store       Store
+	Client      *http.Client
+	Secret      []byte
+	MaxAttempts int
+	Backoff     time.Duration
+	Interval    time.Duration
+
+	now func() time.Time
+}
+
+func MakeDispatcher(store Store, secret string) *Dispatcher {
+	return &Dispatcher{
+		Store:       store,
+		Client:      makeCallbackClient(),
+		Secret:      []byte(secret),
+		MaxAttempts: constants.WebhookMaxAttempts,
+		Backoff:     constants.WebhookInitialBackoff,
+		Interval:    constants.WebhookPollInterval,
+		now:         time.Now,
+	}
+}
+
+// Run delivers the due callbacks until ctx is done. It polls the store every
+// Interval and whenever source announces a finished request.
+func (d *Dispatcher) Run(ctx context.Context, source RequestSource) {
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+	subscription := source.SubscribeRequests()
+
+	for {
+		d.Poll()
+		select {
+		case <-ticker.C:
+		case _, ok := <-subscription.C:
+			if !ok {
+				// Fell behind, the next polls still find every due callback.
+				subscription = source.SubscribeRequests()
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Poll delivers the due callbacks concurrently and returns how many it tried.
+func (d *Dispatcher) Poll() int {
+	webhooks, err := d.Store.ClaimWebhooks(constants.WebhookClaimBatch)
+	if err != nil {
+		fmt.Println("Failed to claim webhooks:", err)
+		return 0
+	}
+
+	var wg sync.WaitGroup
+	for _, webhook := range webhooks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.Deliver(webhook)
+		}()
+	}
+	wg.Wait()
+	return len(webhooks)
+}
+
+// Deliver makes one attempt to send the callback of a finished request and
+// either finishes the callback or schedules the next attempt.
+func (d *Dispatcher) Deliver(webhook db.PendingWebhook) {
+	requestId := webhook.RequestId
+	record, err := d.Store.GetUpdateRequest(requestId)
+	if err != nil {
+		// Due again once the claim expires.
+		fmt.Printf("Failed to load update request %d for webhook: %s\n", requestId, err.Error())
+		return
+	}
+	if record.CallbackUrl == "" || record.Status == db.RequestSubmitted {
+		d.finish(requestId, webhook.Attempts)
+		return
+	}
+
+	body, err := json.Marshal(makePayload(record))
+	if err != nil {
+		fmt.Println("Failed to encode webhook payload:", err)
+		d.finish(requestId, webhook.Attempts)
+		return
+	}
+
+	attempt := webhook.Attempts + 1
+	statusCode, err := d.post(record.CallbackUrl, body)
+
+	recorded := db.WebhookAttempt{
+		RequestId:   requestId,
+		CallbackUrl: record.CallbackUrl,
+		Attempt:     attempt,
+		AttemptedAt: d.now(),
+		StatusCode:  statusCode,
+	}
+	if err != nil {
+		recorded.Error = err.Error()
+	}
+	if recordErr := d.Store.RecordWebhookAttempt(recorded); recordErr != nil {
+		fmt.Println(recordErr.Error())
+	}
+
+	switch {
+	case err == nil || !retryable(statusCode):
+		d.finish(requestId, attempt)
+	case attempt >= d.MaxAttempts:
+		fmt.Printf("Giving up on webhook for update request %d after %d attempts\n", requestId, attempt)
+		d.finish(requestId, attempt)
+	default:
+		backoff := d.Backoff << (attempt - 1)
+		if err := d.Store.RetryWebhook(requestId, attempt, backoff); err != nil {
+			fmt.Println(err.Error())
+		}
+	}
+}
+
+func (d *Dispatcher) finish(requestId uint64, attempts int) {
+	if err := d.Store.FinishWebhook(requestId, attempts); err != nil {
+		fmt.Println(err.Error())
+	}
+}
+
+// post returns an error for transport problems and non-2xx responses.
+func (d *Dispatcher) post(url string, body []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(d.now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(d.Secret, timestamp, body))
+
+	res, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return res.StatusCode, fmt.Errorf("callback responded with %s", res.Status)
+	}
+	return res.StatusCode, nil
+}
+
+// retryable tells whether a failed delivery may succeed later. Transport
+// errors, timeouts, throttling and server errors are retried, other client
+// errors are not.
+func retryable(statusCode int) bool {
+	return statusCode == 0 ||
+		statusCode == http.StatusRequestTimeout ||
+		statusCode == http.StatusTooManyRequests ||
+		statusCode >= 500
+}
+
+// Sign returns the hex HMAC-SHA256 of "<timestamp>.<body>" with secret.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func makePayload(record db.UpdateRequestRecord) Payload {
+	payload := Payload{
+		UpdateID:      record.Id,
+		Pair:          record.Currency1 + "/" + record.Currency2,
+		Status:        record.Status,
+		CompletedAt:   record.CompletedAt,
+		FailureReason: record.FailureReason,
+	}
+	if record.Status == db.RequestOk {
+		payload.Rate = json.Number(utils.FormatDecimal(record.Rate.Rate))
+		payload.UpdateTime = &record.Rate.UpdateTime
+	}
+	return payload
+}
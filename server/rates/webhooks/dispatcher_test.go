@@ -0,0 +1,255 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/artem98/ExchangeRateService/server/rates/db"
+	"github.com/artem98/ExchangeRateService/server/rates/events"
+	"github.com/shopspring/decimal"
+)
+
+// memoryStore claims every due callback at once, regardless of its delay.
+type memoryStore struct {
+	mu       sync.Mutex
+	record   db.UpdateRequestRecord
+	attempts []db.WebhookAttempt
+	due      []db.PendingWebhook
+	delays   []time.Duration
+	finished []db.PendingWebhook
+	claims   int
+}
+
+func (s *memoryStore) GetUpdateRequest(id uint64) (db.UpdateRequestRecord, error) {
+	if id != s.record.Id {
+		return db.UpdateRequestRecord{}, db.ErrNoSuchRequest
+	}
+	return s.record, nil
+}
+
+func (s *memoryStore) RecordWebhookAttempt(attempt db.WebhookAttempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts = append(s.attempts, attempt)
+	return nil
+}
+
+func (s *memoryStore) ClaimWebhooks(limit int) ([]db.PendingWebhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.claims++
+	due := s.due
+	s.due = nil
+	return due, nil
+}
+
+func (s *memoryStore) RetryWebhook(requestId uint64, attempts int, delay time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.due = append(s.due, db.PendingWebhook{RequestId: requestId, Attempts: attempts})
+	s.delays = append(s.delays, delay)
+	return nil
+}
+
+func (s *memoryStore) FinishWebhook(requestId uint64, attempts int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.finished = append(s.finished, db.PendingWebhook{RequestId: requestId, Attempts: attempts})
+	return nil
+}
+
+type channelSource chan events.RequestEvent
+
+func (c channelSource) SubscribeRequests() *events.RequestSubscription {
+	return &events.RequestSubscription{C: c}
+}
+
+func makeTestDispatcher(store Store) *Dispatcher {
+	d := MakeDispatcher(store, "secret")
+	// The test servers listen on loopback, which the callback client refuses.
+	d.Client = &http.Client{}
+	d.now = func() time.Time { return time.Unix(1700000000, 0) }
+	return d
+}
+
+// deliverAll polls until no callback is due.
+func deliverAll(d *Dispatcher) {
+	for d.Poll() > 0 {
+	}
+}
+
+func TestDeliverSignsPayloadAndRetries(t *testing.T) {
+	var calls int
+	var payload Payload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		if got := r.Header.Get(SignatureHeader); got != Sign([]byte("secret"), r.Header.Get(TimestampHeader), body) {
+			t.Errorf("bad signature %q", got)
+		}
+		if r.Header.Get(TimestampHeader) != "1700000000" {
+			t.Errorf("unexpected timestamp %q", r.Header.Get(TimestampHeader))
+		}
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.Unmarshal(body, &payload)
+	}))
+	defer server.Close()
+
+	updateTime := time.Date(2025, 3, 14, 10, 0, 0, 0, time.UTC)
+	store := &memoryStore{record: db.UpdateRequestRecord{
+		Id: 42, Currency1: "EUR", Currency2: "USD", Status: db.RequestOk, CompletedAt: updateTime,
+		Rate:        db.RateRecord{Rate: decimal.RequireFromString("1.0850"), UpdateTime: updateTime},
+		CallbackUrl: server.URL,
+	}, due: []db.PendingWebhook{{RequestId: 42}}}
+	d := makeTestDispatcher(store)
+	deliverAll(d)
+
+	if calls != 3 {
+		t.Fatalf("expected 3 calls, got %d", calls)
+	}
+	if len(store.delays) != 2 || store.delays[0] != d.Backoff || store.delays[1] != 2*d.Backoff {
+		t.Errorf("expected doubling backoff, got %v", store.delays)
+	}
+	if len(store.finished) != 1 || store.finished[0].Attempts != 3 {
+		t.Errorf("expected the callback to finish after 3 attempts, got %+v", store.finished)
+	}
+	if payload.UpdateID != 42 || payload.Pair != "EUR/USD" || payload.Status != db.RequestOk || payload.Rate != "1.0850" {
+		t.Errorf("unexpected payload %+v", payload)
+	}
+	if payload.UpdateTime == nil || !payload.UpdateTime.Equal(updateTime) {
+		t.Errorf("expected update time %v, got %v", updateTime, payload.UpdateTime)
+	}
+
+	if len(store.attempts) != 3 {
+		t.Fatalf("expected 3 recorded attempts, got %d", len(store.attempts))
+	}
+	first, last := store.attempts[0], store.attempts[2]
+	if first.Attempt != 1 || first.StatusCode != http.StatusServiceUnavailable || first.Error == "" {
+		t.Errorf("unexpected first attempt %+v", first)
+	}
+	if last.Attempt != 3 || last.StatusCode != http.StatusOK || last.Error != "" {
+		t.Errorf("unexpected last attempt %+v", last)
+	}
+}
+
+func TestDeliverStopsOnClientError(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer server.Close()
+
+	store := &memoryStore{record: db.UpdateRequestRecord{
+		Id: 7, Currency1: "EUR", Currency2: "USD", Status: db.RequestFailed, FailureReason: "timeout", CallbackUrl: server.URL,
+	}, due: []db.PendingWebhook{{RequestId: 7}}}
+	deliverAll(makeTestDispatcher(store))
+
+	if calls != 1 || len(store.attempts) != 1 || len(store.finished) != 1 {
+		t.Errorf("expected a single attempt, got %d calls and %d records", calls, len(store.attempts))
+	}
+}
+
+func TestDeliverGivesUp(t *testing.T) {
+	store := &memoryStore{record: db.UpdateRequestRecord{
+		Id: 7, Currency1: "EUR", Currency2: "USD", Status: db.RequestOk, CallbackUrl: "http://127.0.0.1:1/unreachable",
+	}, due: []db.PendingWebhook{{RequestId: 7}}}
+	d := makeTestDispatcher(store)
+	deliverAll(d)
+
+	if len(store.attempts) != d.MaxAttempts || len(store.delays) != d.MaxAttempts-1 || len(store.finished) != 1 {
+		t.Errorf("expected %d attempts, got %d with %d retries", d.MaxAttempts, len(store.attempts), len(store.delays))
+	}
+	if store.attempts[0].StatusCode != 0 || store.attempts[0].Error == "" {
+		t.Errorf("expected transport error, got %+v", store.attempts[0])
+	}
+}
+
+func TestDeliverWithoutCallback(t *testing.T) {
+	store := &memoryStore{record: db.UpdateRequestRecord{Id: 7, Status: db.RequestOk}}
+	d := makeTestDispatcher(store)
+	d.Deliver(db.PendingWebhook{RequestId: 7})
+	d.Deliver(db.PendingWebhook{RequestId: 8})
+
+	if len(store.attempts) != 0 {
+		t.Errorf("expected no attempts, got %d", len(store.attempts))
+	}
+	if len(store.finished) != 1 || store.finished[0].RequestId != 7 {
+		t.Errorf("expected only the request without callback to finish, got %+v", store.finished)
+	}
+}
+
+func TestDeliverContinuesCountingStoredAttempts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	// Attempts made before a restart count against the limit.
+	store := &memoryStore{record: db.UpdateRequestRecord{
+		Id: 7, Currency1: "EUR", Currency2: "USD", Status: db.RequestOk, CallbackUrl: server.URL,
+	}}
+	d := makeTestDispatcher(store)
+	d.Deliver(db.PendingWebhook{RequestId: 7, Attempts: d.MaxAttempts - 1})
+
+	if len(store.attempts) != 1 || store.attempts[0].Attempt != d.MaxAttempts {
+		t.Fatalf("expected attempt %d, got %+v", d.MaxAttempts, store.attempts)
+	}
+	if len(store.delays) != 0 || len(store.finished) != 1 {
+		t.Errorf("expected to give up, got %d retries", len(store.delays))
+	}
+}
+
+func TestRunDeliversAnnouncedRequests(t *testing.T) {
+	delivered := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(delivered)
+	}))
+	defer server.Close()
+
+	store := &memoryStore{record: db.UpdateRequestRecord{
+		Id: 7, Currency1: "EUR", Currency2: "USD", Status: db.RequestOk, CallbackUrl: server.URL,
+	}}
+	d := makeTestDispatcher(store)
+	d.Interval = time.Hour
+	source := make(channelSource, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		d.Run(ctx, source)
+		close(stopped)
+	}()
+
+	// The request finishes after the first poll, the event wakes the dispatcher.
+	for {
+		store.mu.Lock()
+		polled := store.claims > 0
+		if polled {
+			store.due = []db.PendingWebhook{{RequestId: 7}}
+		}
+		store.mu.Unlock()
+		if polled {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	source <- events.RequestEvent{RequestId: 7}
+
+	select {
+	case <-delivered:
+	case <-time.After(time.Second):
+		t.Fatal("expected the announced callback to be delivered before the next interval")
+	}
+	cancel()
+	<-stopped
+}
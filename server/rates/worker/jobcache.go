@@ -23,6 +23,14 @@ func MakeRateJobsCache(ttl time.Duration) *RateJobsCache {
 	}
 }
 
+// SetTTL changes how long requests are reused, including the ones already cached.
+func (cache *RateJobsCache) SetTTL(ttl time.Duration) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.ttl = ttl
+}
+
 func (cache *RateJobsCache) Get(currency1, currency2 string) (uint64, bool) {
 	cache.mu.Lock()
 	defer cache.mu.Unlock()
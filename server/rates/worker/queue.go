@@ -0,0 +1,85 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/artem98/ExchangeRateService/server/constants"
+	"github.com/artem98/ExchangeRateService/server/rates/db"
+	"github.com/artem98/ExchangeRateService/server/rates/external"
+)
+
+// Queue plans the submitted update requests nobody is processing. Those are
+// requests left behind by a server that stopped, this one before a restart
+// included, and requests whose lease was given up because the worker was busy.
+// Every server runs a Queue, the database makes sure each request is leased to
+// one of them at a time.
+type Queue struct {
+	Owner     string
+	Db        db.DataBase
+	Worker    *Worker
+	Provider  external.RateProvider
+	Publisher Publisher
+	Retry     RetryPolicy
+	Interval  time.Duration
+}
+
+func MakeQueue(owner string, database db.DataBase, worker *Worker, provider external.RateProvider, publisher Publisher, retry RetryPolicy) *Queue {
+	return &Queue{
+		Owner:     owner,
+		Db:        database,
+		Worker:    worker,
+		Provider:  provider,
+		Publisher: publisher,
+		Retry:     retry,
+		Interval:  constants.QueuePollInterval,
+	}
+}
+
+// Run polls the database until ctx is done.
+func (q *Queue) Run(ctx context.Context) {
+	ticker := time.NewTicker(q.Interval)
+	defer ticker.Stop()
+
+	for {
+		q.Poll()
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Poll claims as many requests as the worker has room for and plans them. It
+// returns how many were planned.
+func (q *Queue) Poll() int {
+	room := min(q.Worker.Room(), constants.QueueClaimBatch)
+	if room == 0 {
+		return 0
+	}
+
+	requests, err := q.Db.ClaimRequests(q.Owner, room)
+	if err != nil {
+		fmt.Println("Failed to claim update requests:", err)
+		return 0
+	}
+
+	planned := 0
+	for _, request := range requests {
+		job := MakeRateUpdateJob(request, q.Owner, q.Retry, q.Db, q.Provider, q.Publisher)
+		if err := q.Worker.PlanJob(job); err != nil {
+			// Let another server, or a later poll, have it.
+			if err := q.Db.ReleaseRequest(request.Id, q.Owner); err != nil {
+				fmt.Println("Failed to release update request:", err)
+			}
+			continue
+		}
+		planned++
+	}
+	if len(requests) > 0 {
+		fmt.Printf("Claimed %d pending update requests, planned %d\n", len(requests), planned)
+	}
+	return planned
+}
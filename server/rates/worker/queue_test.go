@@ -0,0 +1,289 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/artem98/ExchangeRateService/server/rates/db"
+	"github.com/artem98/ExchangeRateService/server/rates/events"
+	"github.com/artem98/ExchangeRateService/server/rates/external"
+	"github.com/shopspring/decimal"
+)
+
+// queueDb keeps update requests in memory. Unused methods panic through the
+// embedded nil interface.
+type queueDb struct {
+	db.DataBase
+	mu        sync.Mutex
+	pending   []db.UpdateRequestRecord
+	leases    map[uint64]string
+	processed map[uint64]bool
+	failed    map[uint64]string
+	retried   map[uint64]time.Duration
+}
+
+func makeQueueDb(requests ...db.UpdateRequestRecord) *queueDb {
+	return &queueDb{
+		pending:   requests,
+		leases:    make(map[uint64]string),
+		processed: make(map[uint64]bool),
+		failed:    make(map[uint64]string),
+		retried:   make(map[uint64]time.Duration),
+	}
+}
+
+func (d *queueDb) ClaimRequests(leaseOwner string, limit int) ([]db.UpdateRequestRecord, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var claimed []db.UpdateRequestRecord
+	for _, request := range d.pending {
+		if len(claimed) == limit {
+			break
+		}
+		if _, leased := d.leases[request.Id]; leased || d.processed[request.Id] {
+			continue
+		}
+		d.leases[request.Id] = leaseOwner
+		claimed = append(claimed, request)
+	}
+	return claimed, nil
+}
+
+func (d *queueDb) RenewLease(requestId uint64, leaseOwner string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.leases[requestId] == leaseOwner && !d.processed[requestId], nil
+}
+
+func (d *queueDb) ReleaseRequest(requestId uint64, leaseOwner string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.leases, requestId)
+	return nil
+}
+
+func (d *queueDb) UpdateRate(currency1, currency2 string, quote external.Quote) (db.RateRecord, error) {
+	return db.RateRecord{Rate: quote.Rate, UpdateTime: time.Now()}, nil
+}
+
+func (d *queueDb) MarkRequestAsProcessed(requestId uint64, leaseOwner string, produced db.RateRecord) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.leases[requestId] != leaseOwner {
+		return db.ErrLeaseLost
+	}
+	d.processed[requestId] = true
+	return nil
+}
+
+func (d *queueDb) MarkRequestAsFailed(requestId uint64, leaseOwner, reason string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.leases[requestId] != leaseOwner {
+		return db.ErrLeaseLost
+	}
+	d.failed[requestId] = reason
+	return nil
+}
+
+func (d *queueDb) RetryRequest(requestId uint64, leaseOwner, reason string, delay time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.leases, requestId)
+	d.retried[requestId] = delay
+	return nil
+}
+
+func (d *queueDb) isProcessed(requestId uint64) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.processed[requestId]
+}
+
+type failingProvider struct {
+	err error
+}
+
+func (failingProvider) Name() string { return "failing" }
+
+func (p failingProvider) FetchRate(currency1, currency2 string) (external.Quote, error) {
+	return external.Quote{}, p.err
+}
+
+type fixedProvider struct{}
+
+func (fixedProvider) Name() string { return "fixed" }
+
+func (fixedProvider) FetchRate(currency1, currency2 string) (external.Quote, error) {
+	return external.Quote{Rate: decimal.RequireFromString("1.5"), Provider: "fixed"}, nil
+}
+
+func waitUntil(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestQueue_PollProcessesPendingRequests(t *testing.T) {
+	database := makeQueueDb(
+		db.UpdateRequestRecord{Id: 1, Currency1: "EUR", Currency2: "USD", Status: db.RequestSubmitted},
+		db.UpdateRequestRecord{Id: 2, Currency1: "GBP", Currency2: "USD", Status: db.RequestSubmitted},
+	)
+	queue := MakeQueue("server-1", database, MakeWorker(2, 100), fixedProvider{}, nil, RetryPolicy{})
+
+	if planned := queue.Poll(); planned != 2 {
+		t.Fatalf("expected 2 planned requests, got %d", planned)
+	}
+	waitUntil(t, func() bool { return database.isProcessed(1) && database.isProcessed(2) })
+
+	if planned := queue.Poll(); planned != 0 {
+		t.Errorf("expected processed requests not to be claimed again, got %d", planned)
+	}
+}
+
+func TestQueue_JobSkipsRequestWithLostLease(t *testing.T) {
+	database := makeQueueDb(db.UpdateRequestRecord{Id: 1, Currency1: "EUR", Currency2: "USD", Status: db.RequestSubmitted})
+	database.leases[1] = "server-2"
+
+	job := MakeRateUpdateJob(database.pending[0], "server-1", RetryPolicy{}, database, fixedProvider{}, nil)
+	if err := job.Run(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if database.isProcessed(1) {
+		t.Errorf("expected request leased to another server not to be processed")
+	}
+}
+
+// stealingProvider hands the lease of the request to another server while the
+// rate is being fetched.
+type stealingProvider struct {
+	database *queueDb
+	err      error
+}
+
+func (stealingProvider) Name() string { return "stealing" }
+
+func (p stealingProvider) FetchRate(currency1, currency2 string) (external.Quote, error) {
+	p.database.mu.Lock()
+	defer p.database.mu.Unlock()
+	p.database.leases[1] = "server-2"
+	return external.Quote{Rate: decimal.RequireFromString("1.5")}, p.err
+}
+
+type recordingPublisher struct {
+	requests []events.RequestEvent
+}
+
+func (p *recordingPublisher) RatesStored() {}
+
+func (p *recordingPublisher) PublishRequest(event events.RequestEvent) {
+	p.requests = append(p.requests, event)
+}
+
+func TestQueue_JobReportsLeaseLostWhileRunning(t *testing.T) {
+	for _, fetchErr := range []error{nil, external.ErrRateNotFound} {
+		database := makeQueueDb(db.UpdateRequestRecord{Id: 1, Currency1: "EUR", Currency2: "USD", Status: db.RequestSubmitted})
+		database.leases[1] = "server-1"
+		publisher := &recordingPublisher{}
+
+		provider := stealingProvider{database: database, err: fetchErr}
+		err := MakeRateUpdateJob(database.pending[0], "server-1", RetryPolicy{}, database, provider, publisher).Run()
+		if !errors.Is(err, db.ErrLeaseLost) {
+			t.Errorf("fetch error %v: expected the lost lease to be reported, got %v", fetchErr, err)
+		}
+		if database.isProcessed(1) || database.failed[1] != "" {
+			t.Errorf("fetch error %v: expected the request not to be finished", fetchErr)
+		}
+		if len(publisher.requests) != 0 {
+			t.Errorf("fetch error %v: expected no request event, got %+v", fetchErr, publisher.requests)
+		}
+	}
+}
+
+func TestQueue_AbandonedJobReleasesRequest(t *testing.T) {
+	database := makeQueueDb(db.UpdateRequestRecord{Id: 1, Currency1: "EUR", Currency2: "USD", Status: db.RequestSubmitted})
+	database.leases[1] = "server-1"
+
+	job := MakeRateUpdateJob(database.pending[0], "server-1", RetryPolicy{}, database, fixedProvider{}, nil)
+	job.Abandon()
+	if held, _ := database.RenewLease(1, "server-1"); held {
+		t.Errorf("expected the request of an abandoned job to be released")
+	}
+}
+
+func TestQueue_PollClaimsNothingWhenWorkerIsFull(t *testing.T) {
+	database := makeQueueDb(db.UpdateRequestRecord{Id: 1, Currency1: "EUR", Currency2: "USD", Status: db.RequestSubmitted})
+	w := MakeWorker(1, 100)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	w.PlanJob(Job{Key: "GBP/USD", Run: func() error {
+		close(started)
+		<-release
+		return nil
+	}})
+	<-started
+	for w.Room() > 0 {
+		w.PlanJob(Job{Key: "GBP/USD", Run: func() error { return nil }})
+	}
+
+	queue := MakeQueue("server-1", database, w, fixedProvider{}, nil, RetryPolicy{})
+	if planned := queue.Poll(); planned != 0 {
+		t.Errorf("expected nothing to be planned, got %d", planned)
+	}
+	if len(database.leases) != 0 {
+		t.Errorf("expected no request to be claimed, got %v", database.leases)
+	}
+}
+
+func TestQueue_JobRetriesTransientFailures(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: time.Minute}
+	transient := failingProvider{err: &external.StatusError{StatusCode: 503, Status: "503 Service Unavailable"}}
+
+	database := makeQueueDb(db.UpdateRequestRecord{Id: 1, Currency1: "EUR", Currency2: "USD", Attempts: 1})
+	database.leases[1] = "server-1"
+	job := MakeRateUpdateJob(database.pending[0], "server-1", policy, database, transient, nil)
+	if err := job.Run(); err == nil {
+		t.Errorf("expected the failed attempt to be reported")
+	}
+	if delay, ok := database.retried[1]; !ok || delay != 2*time.Second {
+		t.Errorf("expected a retry in 2s after the second attempt, got %v", database.retried)
+	}
+	if _, failed := database.failed[1]; failed {
+		t.Errorf("expected the request not to fail while retries are left")
+	}
+
+	// The last allowed attempt fails the request.
+	database = makeQueueDb(db.UpdateRequestRecord{Id: 1, Currency1: "EUR", Currency2: "USD", Attempts: 2})
+	database.leases[1] = "server-1"
+	MakeRateUpdateJob(database.pending[0], "server-1", policy, database, transient, nil).Run()
+	if reason := database.failed[1]; !strings.Contains(reason, "after 3 attempts") {
+		t.Errorf("expected the request to fail after 3 attempts, got %q", reason)
+	}
+}
+
+func TestQueue_JobFailsPermanentErrorsRightAway(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: time.Minute}
+	unknown := failingProvider{err: fmt.Errorf("rate for XXX: %w", external.ErrRateNotFound)}
+
+	database := makeQueueDb(db.UpdateRequestRecord{Id: 1, Currency1: "EUR", Currency2: "XXX"})
+	database.leases[1] = "server-1"
+	MakeRateUpdateJob(database.pending[0], "server-1", policy, database, unknown, nil).Run()
+
+	if len(database.retried) != 0 {
+		t.Errorf("expected no retry, got %v", database.retried)
+	}
+	if _, failed := database.failed[1]; !failed {
+		t.Errorf("expected the request to fail")
+	}
+}
@@ -1,35 +1,103 @@
 package worker
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/artem98/ExchangeRateService/server/rates/db"
+	"github.com/artem98/ExchangeRateService/server/rates/events"
 	"github.com/artem98/ExchangeRateService/server/rates/external"
 )
 
-func MakeRateUpdateJob(currency1, currency2 string, reqId uint64, db db.DataBase) Job {
-	return func() (err error) {
+// Publisher is told about every rate a job stores and every request it finishes.
+type Publisher interface {
+	RatesStored()
+	PublishRequest(event events.RequestEvent)
+}
+
+// MakeRateUpdateJob makes the job processing an update request leased to
+// leaseOwner. The job does nothing if the lease was lost before it started,
+// and reports db.ErrLeaseLost if it was lost before the request was finished.
+// Transient fetch failures are retried as the policy allows: the request goes
+// back to the queue and is claimed again once the retry delay has passed.
+func MakeRateUpdateJob(request db.UpdateRequestRecord, leaseOwner string, retry RetryPolicy, database db.DataBase, provider external.RateProvider, publisher Publisher) Job {
+	reqId, currency1, currency2 := request.Id, request.Currency1, request.Currency2
+	finish := func(status db.RequestStatus) {
+		if publisher != nil {
+			publisher.PublishRequest(events.RequestEvent{
+				RequestId:    reqId,
+				CurrencyPair: db.CurrencyPair{Currency1: currency1, Currency2: currency2},
+				Status:       status,
+			})
+		}
+	}
+	fail := func(cause error) error {
+		if err := database.MarkRequestAsFailed(reqId, leaseOwner, cause.Error()); err != nil {
+			return errors.Join(cause, err)
+		}
+		finish(db.RequestFailed)
+		return cause
+	}
+
+	run := func() (err error) {
 		defer func() {
 			if r := recover(); r != nil {
 				fmt.Println("Recovered in processJob:", r)
-				db.MarkRequestAsFailed(reqId)
-				err = fmt.Errorf("panic occurred: %v", r)
+				err = fail(fmt.Errorf("panic occurred: %v", r))
 			}
 		}()
 
-		rate, err := external.FetchRate(currency1, currency2)
-
+		// The lease may have expired while the job was queued and the request
+		// been claimed again, possibly by this server, and processed already.
+		held, err := database.RenewLease(reqId, leaseOwner)
 		if err != nil {
-			db.MarkRequestAsFailed(reqId)
 			return err
 		}
+		if !held {
+			fmt.Println("Skipping update request", reqId, "which is no longer leased")
+			return nil
+		}
+
+		attempt := request.Attempts + 1
+		quote, err := provider.FetchRate(currency1, currency2)
 
-		err = db.UpdateRate(currency1, currency2, rate)
 		if err != nil {
-			db.MarkRequestAsFailed(reqId)
-			return err
+			if !external.IsRetryable(err) {
+				return fail(err)
+			}
+			if !retry.ShouldRetry(attempt) {
+				return fail(fmt.Errorf("giving up after %d attempts: %w", attempt, err))
+			}
+			delay := retry.Delay(attempt)
+			if retryErr := database.RetryRequest(reqId, leaseOwner, err.Error(), delay); retryErr != nil {
+				return retryErr
+			}
+			return fmt.Errorf("attempt %d failed, retrying in %s: %w", attempt, delay.Round(time.Millisecond), err)
+		}
+
+		record, err := database.UpdateRate(currency1, currency2, quote)
+		if err != nil {
+			return fail(err)
+		}
+		if publisher != nil {
+			publisher.RatesStored()
 		}
 
-		return db.MarkRequestAsProcessed(reqId)
+		err = database.MarkRequestAsProcessed(reqId, leaseOwner, record)
+		if err != nil {
+			return err
+		}
+		finish(db.RequestOk)
+		return nil
+	}
+	// A request whose job never started goes back to the queue right away
+	// instead of waiting for its lease to expire.
+	abandon := func() {
+		if err := database.ReleaseRequest(reqId, leaseOwner); err != nil {
+			fmt.Println(err.Error())
+		}
 	}
+	// Updates of the same pair are serialized, so they never race in UpdateRate.
+	return Job{Key: currency1 + "/" + currency2, Run: run, Abandon: abandon}
 }
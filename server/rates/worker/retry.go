@@ -0,0 +1,52 @@
+package worker
+
+import (
+	"math/rand/v2"
+	"time"
+
+	"github.com/artem98/ExchangeRateService/server/constants"
+)
+
+// RetryPolicy decides how often and how late a rate update is tried again
+// after a transient failure. The delay doubles with every attempt up to
+// MaxDelay and is spread by up to Jitter of itself in both directions, so
+// requests failing together do not all come back at once. The zero value
+// never retries.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Jitter      float64
+
+	random func() float64
+}
+
+func MakeRetryPolicy(maxAttempts int, baseDelay, maxDelay time.Duration) RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: maxAttempts,
+		BaseDelay:   baseDelay,
+		MaxDelay:    maxDelay,
+		Jitter:      constants.RetryJitter,
+	}
+}
+
+// ShouldRetry reports whether another attempt may follow attempt, counted from 1.
+func (p RetryPolicy) ShouldRetry(attempt int) bool {
+	return attempt < p.MaxAttempts
+}
+
+// Delay returns the pause between attempt, counted from 1, and the next one.
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, p.MaxDelay)
+
+	random := p.random
+	if random == nil {
+		random = rand.Float64
+	}
+	spread := float64(delay) * p.Jitter * (2*random() - 1)
+	return delay + time.Duration(spread)
+}
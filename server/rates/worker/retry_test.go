@@ -0,0 +1,37 @@
+package worker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: time.Second, MaxDelay: 5 * time.Second, Jitter: 0.5}
+
+	policy.random = func() float64 { return 0.5 }
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, want := range expected {
+		if got := policy.Delay(i + 1); got != want {
+			t.Errorf("attempt %d: expected %s, got %s", i+1, want, got)
+		}
+	}
+
+	policy.random = func() float64 { return 0 }
+	if got := policy.Delay(2); got != time.Second {
+		t.Errorf("expected lowest jitter to halve the delay, got %s", got)
+	}
+	policy.random = func() float64 { return 1 }
+	if got := policy.Delay(2); got != 3*time.Second {
+		t.Errorf("expected highest jitter to add half the delay, got %s", got)
+	}
+}
+
+func TestRetryPolicy_ShouldRetry(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3}
+	if !policy.ShouldRetry(2) || policy.ShouldRetry(3) {
+		t.Errorf("expected retries after attempts 1 and 2 only")
+	}
+	if (RetryPolicy{}).ShouldRetry(1) {
+		t.Errorf("expected the zero policy never to retry")
+	}
+}
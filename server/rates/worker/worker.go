@@ -1,39 +1,228 @@
 package worker
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/artem98/ExchangeRateService/server/constants"
 )
 
-type Job = func() error
+// Job is a unit of work for the Worker. Jobs with the same Key never run at
+// the same time and are run in the order they were planned.
+type Job struct {
+	Key string
+	Run func() error
+	// Abandon, when set, is called instead of Run for a job the worker drops
+	// because it is stopping, so the job can be handed back to a durable queue.
+	Abandon func()
+}
+
+var (
+	ErrQueueFull     = errors.New("update queue is full")
+	ErrWorkerStopped = errors.New("worker is stopped")
+)
 
+// Worker runs planned jobs on a fixed number of goroutines.
 type Worker struct {
-	hasStarted bool
-	jobs       chan Job
+	size  int
+	start sync.Once
+	jobs  chan Job
+	loops sync.WaitGroup
+	// slots bounds the jobs waiting to run, whether they are still in jobs or
+	// wait behind a job with the same key. A slot is freed when its job starts.
+	slots chan struct{}
+
+	// planning is held for reading while a job is queued, so Stop can close
+	// jobs once no PlanJob is sending to it.
+	planning sync.RWMutex
+	stopped  bool
+	// quit tells the goroutines to leave the remaining jobs alone.
+	quit chan struct{}
+
+	mu sync.Mutex
+	// running holds the keys of jobs being processed, along with the jobs
+	// planned for the same key that wait for them.
+	running   map[string][]Job
+	abandoned int
 }
 
-func MakeWorker() *Worker {
-	return &Worker{hasStarted: false, jobs: make(chan Job, constants.WorkerQueueSize)}
+func MakeWorker(size, queueSize int) *Worker {
+	if size < 1 {
+		size = 1
+	}
+	return &Worker{
+		size:    size,
+		jobs:    make(chan Job, queueSize),
+		slots:   make(chan struct{}, queueSize),
+		quit:    make(chan struct{}),
+		running: make(map[string][]Job),
+	}
 }
 
-func (w *Worker) PlanJob(job Job) {
-	if !w.hasStarted {
-		w.start()
+// Room returns how many jobs can be planned without waiting.
+func (w *Worker) Room() int {
+	return cap(w.slots) - len(w.slots)
+}
+
+// PlanJob queues the job, waiting at most constants.WorkerQueueWait for room
+// in the queue. It returns ErrQueueFull when the job was not queued, and
+// ErrWorkerStopped once Stop has been called.
+func (w *Worker) PlanJob(job Job) error {
+	w.start.Do(w.startLoops)
+
+	w.planning.RLock()
+	defer w.planning.RUnlock()
+	if w.stopped {
+		return ErrWorkerStopped
+	}
+
+	timer := time.NewTimer(constants.WorkerQueueWait)
+	defer timer.Stop()
+	select {
+	case w.slots <- struct{}{}:
+		// Never blocks, jobs has room for every slot.
+		w.jobs <- job
+		return nil
+	case <-timer.C:
+		return ErrQueueFull
 	}
-	w.jobs <- job
 }
 
-func (w *Worker) start() {
-	w.hasStarted = true
+// Stop stops accepting jobs and lets the goroutines process the queued ones
+// until ctx is done. The jobs left then, queued or waiting behind a job with
+// the same key, are abandoned, and Stop returns how many. Jobs running at that
+// point are not interrupted. Stop must only be called once.
+func (w *Worker) Stop(ctx context.Context) int {
+	// Nothing starts the goroutines from now on.
+	w.start.Do(func() {})
+
+	w.planning.Lock()
+	w.stopped = true
+	close(w.jobs)
+	w.planning.Unlock()
+
+	drained := make(chan struct{})
 	go func() {
-		for job := range w.jobs {
+		w.loops.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		return 0
+	case <-ctx.Done():
+		close(w.quit)
+	}
+
+	// jobs is closed, so this only takes what the goroutines have not.
+	for job := range w.jobs {
+		w.abandon(job)
+	}
+
+	w.mu.Lock()
+	var waiting []Job
+	for key, jobs := range w.running {
+		waiting = append(waiting, jobs...)
+		w.running[key] = nil
+	}
+	w.mu.Unlock()
+	for _, job := range waiting {
+		w.abandon(job)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.abandoned
+}
+
+func (w *Worker) abandon(job Job) {
+	w.mu.Lock()
+	w.abandoned++
+	w.mu.Unlock()
+
+	if job.Abandon != nil {
+		job.Abandon()
+	}
+}
+
+func (w *Worker) startLoops() {
+	w.loops.Add(w.size)
+	for i := 0; i < w.size; i++ {
+		go w.loop()
+	}
+}
+
+func (w *Worker) quitting() bool {
+	select {
+	case <-w.quit:
+		return true
+	default:
+		return false
+	}
+}
+
+func (w *Worker) loop() {
+	defer w.loops.Done()
+
+	for job := range w.jobs {
+		run, quitting := w.acquire(job)
+		if quitting {
+			w.abandon(job)
+			return
+		}
+		if !run {
+			// Another goroutine is busy with this key and will run the job after its own.
+			continue
+		}
+		for {
 			err := w.processJob(job)
 			if err != nil {
-				fmt.Printf("Failed to process job %v : %s", job, err.Error())
+				fmt.Printf("Failed to process job %s : %s\n", job.Key, err.Error())
+			}
+			if w.quitting() {
+				return
+			}
+			var ok bool
+			if job, ok = w.release(job.Key); !ok {
+				break
 			}
 		}
-	}()
+	}
+}
+
+// acquire marks the job's key as running, or queues the job behind the one
+// already running for that key and reports false. Once Stop gave up waiting
+// it does neither and reports quitting, as the waiting jobs were taken already.
+func (w *Worker) acquire(job Job) (run, quitting bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.quitting() {
+		return false, true
+	}
+	if waiting, busy := w.running[job.Key]; busy {
+		w.running[job.Key] = append(waiting, job)
+		return false, false
+	}
+	w.running[job.Key] = nil
+	return true, false
+}
+
+// release returns the next job waiting for the key, or frees the key when
+// there is none.
+func (w *Worker) release(key string) (Job, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	waiting := w.running[key]
+	if len(waiting) == 0 {
+		delete(w.running, key)
+		return Job{}, false
+	}
+	w.running[key] = waiting[1:]
+	return waiting[0], true
 }
 
 func (w *Worker) processJob(job Job) (err error) {
@@ -44,7 +233,8 @@ func (w *Worker) processJob(job Job) (err error) {
 		}
 	}()
 
-	fmt.Println("Worker starts processing new job")
+	<-w.slots
+	fmt.Println("Worker starts processing new job", job.Key)
 
-	return job()
+	return job.Run()
 }
@@ -0,0 +1,236 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorker_SerializesJobsWithSameKey(t *testing.T) {
+	w := MakeWorker(4, 100)
+
+	var wg sync.WaitGroup
+	var running atomic.Int32
+	var mu sync.Mutex
+	var order []int
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		w.PlanJob(Job{Key: "USD/EUR", Run: func() error {
+			defer wg.Done()
+			if running.Add(1) != 1 {
+				t.Errorf("two jobs for the same key run at once")
+			}
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+			running.Add(-1)
+			return nil
+		}})
+	}
+	wg.Wait()
+
+	for i, got := range order {
+		if got != i {
+			t.Fatalf("expected jobs to run in planned order, got %v", order)
+		}
+	}
+}
+
+func TestWorker_RunsDifferentKeysConcurrently(t *testing.T) {
+	w := MakeWorker(2, 100)
+
+	release := make(chan struct{})
+	started := make(chan string, 2)
+	var wg sync.WaitGroup
+	for _, key := range []string{"USD/EUR", "GBP/JPY"} {
+		wg.Add(1)
+		w.PlanJob(Job{Key: key, Run: func() error {
+			defer wg.Done()
+			started <- key
+			<-release
+			return nil
+		}})
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+			t.Fatal("expected both jobs to start while the other is blocked")
+		}
+	}
+	close(release)
+	wg.Wait()
+}
+
+func TestWorker_KeepsRunningAfterPanic(t *testing.T) {
+	w := MakeWorker(1, 100)
+
+	done := make(chan struct{})
+	w.PlanJob(Job{Key: "USD/EUR", Run: func() error { panic("boom") }})
+	w.PlanJob(Job{Key: "USD/EUR", Run: func() error {
+		close(done)
+		return nil
+	}})
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected the job after a panicking one to run")
+	}
+}
+
+func TestWorker_PlanJobReturnsErrQueueFull(t *testing.T) {
+	const queueSize = 10
+	w := MakeWorker(1, queueSize)
+
+	release := make(chan struct{})
+	defer close(release)
+	blocked := Job{Key: "USD/EUR", Run: func() error {
+		<-release
+		return nil
+	}}
+
+	// The running job and a full queue: the first plan that does not fit fails.
+	var err error
+	planned := 0
+	for planned <= queueSize+1 {
+		if err = w.PlanJob(blocked); err != nil {
+			break
+		}
+		planned++
+	}
+	if !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull after %d jobs, got %v", planned, err)
+	}
+}
+
+func TestWorker_StopProcessesQueuedJobs(t *testing.T) {
+	w := MakeWorker(1, 100)
+
+	var processed atomic.Int32
+	for i := 0; i < 5; i++ {
+		w.PlanJob(Job{Key: "USD/EUR", Run: func() error {
+			time.Sleep(time.Millisecond)
+			processed.Add(1)
+			return nil
+		}})
+	}
+
+	if left := w.Stop(context.Background()); left != 0 {
+		t.Fatalf("expected no jobs left, got %d", left)
+	}
+	if processed.Load() != 5 {
+		t.Fatalf("expected 5 jobs processed before Stop returned, got %d", processed.Load())
+	}
+	if err := w.PlanJob(Job{Key: "USD/EUR", Run: func() error { return nil }}); !errors.Is(err, ErrWorkerStopped) {
+		t.Fatalf("expected ErrWorkerStopped after Stop, got %v", err)
+	}
+}
+
+func TestWorker_StopAbandonsJobsLeftAtDeadline(t *testing.T) {
+	// The second goroutine parks the jobs behind the running one.
+	w := MakeWorker(2, 100)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	w.PlanJob(Job{Key: "USD/EUR", Run: func() error {
+		close(started)
+		<-release
+		return nil
+	}})
+	<-started
+	var abandoned atomic.Int32
+	for i := 0; i < 3; i++ {
+		w.PlanJob(Job{
+			Key:     "USD/EUR",
+			Run:     func() error { return nil },
+			Abandon: func() { abandoned.Add(1) },
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if left := w.Stop(ctx); left != 3 {
+		t.Fatalf("expected 3 jobs left, got %d", left)
+	}
+	if abandoned.Load() != 3 {
+		t.Fatalf("expected 3 jobs abandoned, got %d", abandoned.Load())
+	}
+	close(release)
+}
+
+func TestWorker_StopWithoutJobs(t *testing.T) {
+	w := MakeWorker(2, 100)
+	if left := w.Stop(context.Background()); left != 0 {
+		t.Fatalf("expected no jobs left, got %d", left)
+	}
+}
+
+func TestWorker_JobsWaitingForTheirKeyCountAgainstTheQueue(t *testing.T) {
+	const queueSize = 10
+	w := MakeWorker(4, queueSize)
+
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{}, 1)
+	blocked := Job{Key: "USD/EUR", Run: func() error {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		return nil
+	}}
+
+	if err := w.PlanJob(blocked); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	// The other goroutines take these off the queue and park them behind the
+	// running job, which must not make room for more.
+	for i := 0; i < queueSize; i++ {
+		if err := w.PlanJob(blocked); err != nil {
+			t.Fatalf("expected job %d to fit in the queue, got %v", i+1, err)
+		}
+	}
+	if err := w.PlanJob(blocked); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+	if room := w.Room(); room != 0 {
+		t.Fatalf("expected no room, got %d", room)
+	}
+}
+
+// BenchmarkWorker plans jobs for distinct pairs that wait like a slow provider
+// call. Throughput (jobs/s) should grow with the number of workers.
+func BenchmarkWorker(b *testing.B) {
+	const jobsPerRound = 64
+	const callDuration = 2 * time.Millisecond
+
+	for _, size := range []int{1, 2, 4, 8, 16} {
+		b.Run(fmt.Sprintf("workers=%d", size), func(b *testing.B) {
+			w := MakeWorker(size, 100)
+			b.Cleanup(func() { w.Stop(context.Background()) })
+			start := time.Now()
+			for n := 0; n < b.N; n++ {
+				var wg sync.WaitGroup
+				wg.Add(jobsPerRound)
+				for i := 0; i < jobsPerRound; i++ {
+					w.PlanJob(Job{Key: fmt.Sprintf("PAIR%d", i), Run: func() error {
+						defer wg.Done()
+						time.Sleep(callDuration)
+						return nil
+					}})
+				}
+				wg.Wait()
+			}
+			b.ReportMetric(float64(b.N*jobsPerRound)/time.Since(start).Seconds(), "jobs/s")
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+
+	"github.com/artem98/ExchangeRateService/server/config"
+	"github.com/artem98/ExchangeRateService/server/rates/external"
+	"github.com/artem98/ExchangeRateService/server/rates/scheduler"
+	"github.com/artem98/ExchangeRateService/server/rates/worker"
+)
+
+// reloader loads the configuration again on SIGHUP or POST /admin/reload and
+// applies the cache TTL, the providers and the refresh schedules to the running
+// server. Connections stay open and queued jobs are kept; settings that need a
+// restart are only reported. The server does not limit the rate of API calls,
+// so there are no rate limits to reload.
+type reloader struct {
+	args      []string
+	cache     *worker.RateJobsCache
+	provider  *external.ReloadableProvider
+	refresher *scheduler.Scheduler
+
+	mu sync.Mutex
+	// started is the configuration the server was started with, current the
+	// one applied by the latest reload.
+	started config.Config
+	current config.Config
+}
+
+type reloadResponse struct {
+	RestartRequired []string `json:"restart_required"`
+}
+
+func makeReloader(args []string, cfg config.Config, cache *worker.RateJobsCache, provider *external.ReloadableProvider, refresher *scheduler.Scheduler) *reloader {
+	return &reloader{
+		args:      args,
+		cache:     cache,
+		provider:  provider,
+		refresher: refresher,
+		started:   cfg,
+		current:   cfg,
+	}
+}
+
+// reload applies the new configuration and returns the changed settings that
+// wait for a restart. Nothing is applied when any setting is invalid.
+func (r *reloader) reload() ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := config.Load(r.args)
+	if err != nil {
+		return nil, err
+	}
+	entries, publication, err := refreshEntries(next.Refresh)
+	if err != nil {
+		return nil, err
+	}
+
+	var chain external.RateProvider
+	providers := next.Providers
+	if !slices.Equal(providers.Names, r.current.Providers.Names) ||
+		providers.Aggregation != r.current.Providers.Aggregation ||
+		providers.Options() != r.current.Providers.Options() {
+		// Rebuilt only when changed, as it resets the health of the providers.
+		chain, err = external.MakeProviderChain(providers.Names, providers.Aggregation, providers.Options())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	r.cache.SetTTL(next.Worker.CacheTTL)
+	if chain != nil {
+		fmt.Println("Using rate provider", chain.Name())
+		r.provider.Set(chain)
+	}
+	r.refresher.Reload(entries, publication)
+	r.current = next
+
+	fmt.Printf("Reloaded configuration:\n%s", next)
+	restart := r.started.NeedsRestart(next)
+	if len(restart) > 0 {
+		fmt.Println("Changes of these settings apply after a restart:", restart)
+	}
+	return restart, nil
+}
+
+// watchHangups reloads on every signal received from hangups, which the caller
+// registers for SIGHUP early, as the signal ends a process not handling it.
+func (r *reloader) watchHangups(ctx context.Context, hangups chan os.Signal) {
+	defer signal.Stop(hangups)
+
+	for {
+		select {
+		case <-hangups:
+			if _, err := r.reload(); err != nil {
+				fmt.Println("Failed to reload configuration:", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (r *reloader) handleReload(w http.ResponseWriter, req *http.Request) {
+	token := []byte("Bearer " + r.started.Server.AdminToken)
+	if subtle.ConstantTimeCompare([]byte(req.Header.Get("Authorization")), token) != 1 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	restart, err := r.reload()
+	if err != nil {
+		fmt.Println("Failed to reload configuration:", err)
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	if restart == nil {
+		restart = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reloadResponse{RestartRequired: restart})
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/artem98/ExchangeRateService/server/config"
+	"github.com/artem98/ExchangeRateService/server/rates/external"
+	"github.com/artem98/ExchangeRateService/server/rates/scheduler"
+	"github.com/artem98/ExchangeRateService/server/rates/worker"
+)
+
+const startingConfig = `
+worker:
+  cache_ttl: 1h
+providers:
+  names: [fake]
+refresh:
+  publication_schedule: none
+`
+
+// testReloader starts a reloader from startingConfig, with the admin token
+// secret given by the environment. The returned function replaces the config
+// file.
+func testReloader(t *testing.T) (*reloader, *worker.RateJobsCache, func(content string)) {
+	t.Helper()
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("ADMIN_TOKEN", "secret")
+	path := filepath.Join(t.TempDir(), "server.yaml")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(startingConfig)
+
+	args := []string{"-config", path}
+	cfg, err := config.Load(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := worker.MakeRateJobsCache(cfg.Worker.CacheTTL)
+	provider := external.MakeReloadableProvider(external.MakeFakeProvider(0))
+	refresher := scheduler.MakeScheduler(nil, nil, nil, nil, nil)
+	return makeReloader(args, cfg, cache, provider, refresher), cache, write
+}
+
+func TestReloader_Reload(t *testing.T) {
+	for _, c := range []struct {
+		name        string
+		config      string
+		wantErr     bool
+		wantRestart []string
+		check       func(t *testing.T, r *reloader, cache *worker.RateJobsCache)
+	}{
+		{
+			name: "cache ttl",
+			config: `
+worker:
+  cache_ttl: 1ms
+providers:
+  names: [fake]
+`,
+			check: func(t *testing.T, r *reloader, cache *worker.RateJobsCache) {
+				cache.Set("EUR", "USD", 1)
+				time.Sleep(10 * time.Millisecond)
+				if _, found := cache.Get("EUR", "USD"); found {
+					t.Errorf("expected the request to expire with the reloaded TTL")
+				}
+			},
+		},
+		{
+			name: "providers",
+			config: `
+providers:
+  names: [fake, file]
+`,
+			check: func(t *testing.T, r *reloader, cache *worker.RateJobsCache) {
+				if name := r.provider.Name(); name != external.FallbackProviderName {
+					t.Errorf("expected the reloaded fallback chain, got %s", name)
+				}
+			},
+		},
+		{
+			name: "schedules",
+			config: `
+providers:
+  names: [fake]
+refresh:
+  schedules: "EUR/USD,GBP/USD=@every 1m; USD/MXN=@hourly"
+  publication_schedule: "0 15 * * 1-5"
+`,
+			check: func(t *testing.T, r *reloader, cache *worker.RateJobsCache) {
+				if len(r.refresher.Entries) != 2 || r.refresher.Publication == nil {
+					t.Errorf("expected the reloaded schedules, got %d entries", len(r.refresher.Entries))
+				}
+			},
+		},
+		{
+			name: "settings needing a restart",
+			config: `
+server:
+  http_address: ":7000"
+worker:
+  pool_size: 8
+  cache_ttl: 1h
+providers:
+  names: [fake]
+retry:
+  max_attempts: 1
+`,
+			wantRestart: []string{"server", "worker.pool_size", "retry"},
+			check: func(t *testing.T, r *reloader, cache *worker.RateJobsCache) {
+				if r.started.Server.HttpAddress != ":8080" || r.started.Worker.PoolSize != 4 {
+					t.Errorf("expected the started settings to be kept, got %+v", r.started)
+				}
+			},
+		},
+		{
+			name: "invalid",
+			config: `
+worker:
+  cache_ttl: 1ms
+providers:
+  names: [fake, ecb]
+`,
+			wantErr: true,
+			check: func(t *testing.T, r *reloader, cache *worker.RateJobsCache) {
+				cache.Set("EUR", "USD", 1)
+				time.Sleep(10 * time.Millisecond)
+				if _, found := cache.Get("EUR", "USD"); !found {
+					t.Errorf("expected the TTL not to change")
+				}
+				if name := r.provider.Name(); name != external.FakeProviderName {
+					t.Errorf("expected the provider not to change, got %s", name)
+				}
+			},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			r, cache, write := testReloader(t)
+			write(c.config)
+
+			restart, err := r.reload()
+			if (err != nil) != c.wantErr {
+				t.Fatalf("expected error %v, got %v", c.wantErr, err)
+			}
+			if !slices.Equal(restart, c.wantRestart) {
+				t.Errorf("expected %v to need a restart, got %v", c.wantRestart, restart)
+			}
+			c.check(t, r, cache)
+		})
+	}
+}
+
+func TestReloader_ReportsRestartUntilRestarted(t *testing.T) {
+	r, _, write := testReloader(t)
+	write(startingConfig + "retry:\n  max_attempts: 1\n")
+
+	for i := 0; i < 2; i++ {
+		if restart, err := r.reload(); err != nil || !slices.Equal(restart, []string{"retry"}) {
+			t.Fatalf("reload %d: expected retry to need a restart, got %v, %v", i+1, restart, err)
+		}
+	}
+}
+
+func TestReloader_HandleReload(t *testing.T) {
+	for _, c := range []struct {
+		name          string
+		authorization string
+		wantStatus    int
+	}{
+		{"missing token", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer other", http.StatusUnauthorized},
+		{"token without scheme", "secret", http.StatusUnauthorized},
+		{"token", "Bearer secret", http.StatusOK},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			r, _, write := testReloader(t)
+			write("worker:\n  cache_ttl: 1m\nproviders:\n  names: [fake]\n")
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/reload", nil)
+			if c.authorization != "" {
+				req.Header.Set("Authorization", c.authorization)
+			}
+			w := httptest.NewRecorder()
+			r.handleReload(w, req)
+
+			if w.Code != c.wantStatus {
+				t.Fatalf("expected %d, got %d", c.wantStatus, w.Code)
+			}
+			if w.Code != http.StatusOK {
+				if r.current.Worker.CacheTTL != time.Hour {
+					t.Errorf("expected nothing to be reloaded")
+				}
+				return
+			}
+			var resp reloadResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.RestartRequired == nil || len(resp.RestartRequired) != 0 {
+				t.Errorf("expected an empty restart_required, got %v", resp.RestartRequired)
+			}
+			if r.current.Worker.CacheTTL != time.Minute {
+				t.Errorf("expected the cache TTL to be reloaded, got %v", r.current.Worker.CacheTTL)
+			}
+		})
+	}
+}
+
+func TestReloader_HandleReloadRejectsInvalidConfig(t *testing.T) {
+	r, _, write := testReloader(t)
+	write("worker:\n  cache_ttl: soon\n")
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/reload", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	r.handleReload(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d", w.Code)
+	}
+}